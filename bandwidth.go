@@ -0,0 +1,179 @@
+package fbxapi
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateWindow applies Rate every day from the From hour to the To hour
+// excluded, wrapping around midnight when To is lower than From. To may be
+// 24.
+type RateWindow struct {
+	From int
+	To   int
+	Rate int64
+}
+
+func (w RateWindow) contains(hour int) bool {
+	if w.From <= w.To {
+		return hour >= w.From && hour < w.To
+	}
+	return hour >= w.From || hour < w.To
+}
+
+// RateSchedule is a list of windows, the first one containing the current
+// hour wins.
+type RateSchedule []RateWindow
+
+// RateAt returns the rate applied at t, ok is false when no window contains
+// it.
+func (s RateSchedule) RateAt(t time.Time) (rate int64, ok bool) {
+	for _, w := range s {
+		if w.contains(t.Hour()) {
+			return w.Rate, true
+		}
+	}
+	return 0, false
+}
+
+// RateLimiter caps a byte rate. It is safe for concurrent use, transfers
+// sharing a limiter share its rate. Rates are in bytes per second, 0 meaning
+// unlimited.
+type RateLimiter struct {
+	mutex    sync.Mutex
+	rate     int64
+	schedule RateSchedule
+	// next is when the bytes already let through are spent at the rate.
+	next time.Time
+}
+
+func NewRateLimiter(rate int64) *RateLimiter {
+	return &RateLimiter{rate: rate}
+}
+
+// SetRate changes the rate applied outside of the schedule windows, running
+// transfers follow it from their next read.
+func (l *RateLimiter) SetRate(rate int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rate = rate
+}
+
+// SetSchedule makes the limiter follow schedule, nil to only use the rate
+// given to SetRate.
+func (l *RateLimiter) SetSchedule(schedule RateSchedule) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.schedule = schedule
+}
+
+// Rate returns the rate applied now.
+func (l *RateLimiter) Rate() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rateAt(time.Now())
+}
+
+func (l *RateLimiter) rateAt(t time.Time) int64 {
+	if rate, ok := l.schedule.RateAt(t); ok {
+		return rate
+	}
+	return l.rate
+}
+
+// WaitN blocks until n more bytes can be transferred. When ctx is done first,
+// the reservation is given back to the other transfers.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	now := time.Now()
+	rate := l.rateAt(now)
+	if rate <= 0 {
+		l.next = now
+		l.mutex.Unlock()
+		return nil
+	}
+
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	cost := time.Duration(n) * time.Second / time.Duration(rate)
+	l.next = l.next.Add(cost)
+	l.mutex.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mutex.Lock()
+		l.next = l.next.Add(-cost)
+		l.mutex.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limitedReader throttles the reads of r with every non nil limiter.
+type limitedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*RateLimiter
+}
+
+func newLimitedReader(ctx context.Context, r io.Reader, limiters ...*RateLimiter) io.Reader {
+	lr := &limitedReader{ctx: ctx, r: r}
+	for _, l := range limiters {
+		if l != nil {
+			lr.limiters = append(lr.limiters, l)
+		}
+	}
+	if len(lr.limiters) == 0 {
+		return r
+	}
+	return lr
+}
+
+func (lr *limitedReader) Read(p []byte) (n int, err error) {
+	n, err = lr.r.Read(p)
+	for _, l := range lr.limiters {
+		if waitErr := l.WaitN(lr.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return
+}
+
+// limitedBody throttles a response body while keeping its Close.
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// SetDownloadLimit sets the limiter shared by all the downloads of the
+// client, nil removing the limit.
+func (c *Client) SetDownloadLimit(limiter *RateLimiter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.downloadLimit = limiter
+}
+
+// SetUploadLimit sets the limiter shared by all the uploads of the client,
+// nil removing the limit.
+func (c *Client) SetUploadLimit(limiter *RateLimiter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.uploadLimit = limiter
+}
+
+func (c *Client) limits() (download, upload *RateLimiter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.downloadLimit, c.uploadLimit
+}
@@ -0,0 +1,103 @@
+package fbxapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100000)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		failOnError(t, limiter.WaitN(ctx, 10000))
+	}
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Fatalf("50kB went through in %v at 100kB/s", elapsed)
+	}
+
+	limiter.SetRate(0)
+	start = time.Now()
+	failOnError(t, limiter.WaitN(ctx, 1<<30))
+	failOnError(t, limiter.WaitN(ctx, 1<<30))
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("unlimited limiter waited %v", elapsed)
+	}
+
+	limiter.SetRate(1)
+	failOnError(t, limiter.WaitN(ctx, 10))
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+	failOnError(t, limiter.WaitN(context.Background(), 1000))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 100000); err != context.DeadlineExceeded {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if wait := time.Until(limiter.next); wait > time.Second {
+		t.Fatalf("cancelled reservation kept, next transfer waits %v", wait)
+	}
+}
+
+func TestRateSchedule(t *testing.T) {
+	schedule := RateSchedule{
+		{From: 9, To: 18, Rate: 1000},
+		{From: 22, To: 6, Rate: 0},
+	}
+
+	day := func(hour int) time.Time {
+		return time.Date(2018, 1, 2, hour, 30, 0, 0, time.Local)
+	}
+
+	if rate, ok := schedule.RateAt(day(10)); !ok || rate != 1000 {
+		t.Fatalf("unexpected office hours rate %d", rate)
+	}
+	if _, ok := schedule.RateAt(day(3)); !ok {
+		t.Fatal("night window does not wrap around midnight")
+	}
+	if _, ok := schedule.RateAt(day(19)); ok {
+		t.Fatal("evening matched a window")
+	}
+
+	limiter := NewRateLimiter(5000)
+	limiter.SetSchedule(schedule)
+	if rate := limiter.rateAt(day(10)); rate != 1000 {
+		t.Fatalf("schedule not applied, rate %d", rate)
+	}
+	if rate := limiter.rateAt(day(19)); rate != 5000 {
+		t.Fatalf("base rate not applied, rate %d", rate)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	r := bytes.NewReader(make([]byte, 100))
+	if newLimitedReader(context.Background(), r, nil, nil) != io.Reader(r) {
+		t.Fatal("reader wrapped without limiter")
+	}
+
+	limited := newLimitedReader(context.Background(), r, NewRateLimiter(1000))
+	buf := make([]byte, 50)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		_, err := io.ReadFull(limited, buf)
+		failOnError(t, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("100 bytes read in %v at 1kB/s", elapsed)
+	}
+}
@@ -2,10 +2,13 @@ package fbxapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -25,9 +28,11 @@ type App struct {
 }
 
 type Client struct {
-	http    *http.Client
-	mutex   sync.Mutex
-	session *Session
+	http          *http.Client
+	mutex         sync.Mutex
+	session       *Session
+	downloadLimit *RateLimiter
+	uploadLimit   *RateLimiter
 }
 
 type Session struct {
@@ -59,12 +64,8 @@ type Query struct {
 	body           []byte
 	rawAPIResponse *APIResponse
 	contentType    string
-}
-
-var tmpl *template.Template
-
-func init() {
-	tmpl = template.New("url")
+	headers        http.Header
+	ctx            context.Context
 }
 
 func (c *Client) Query(ep *Endpoint) Query {
@@ -100,8 +101,11 @@ func (q Query) WithFormBody(body interface{}) Query {
 	bodyJSON, err := json.Marshal(body)
 	checkErr(err)
 
+	// Numbers are kept as json.Number so integers are not sent as floats.
 	m := make(map[string]interface{})
-	err = json.Unmarshal(bodyJSON, &m)
+	decoder := json.NewDecoder(bytes.NewReader(bodyJSON))
+	decoder.UseNumber()
+	err = decoder.Decode(&m)
 	checkErr(err)
 
 	values := stringify(m)
@@ -110,6 +114,46 @@ func (q Query) WithFormBody(body interface{}) Query {
 	return q
 }
 
+// WithMultipartBody sends fields and the content of r as a multipart form, r
+// being the part named fileField.
+func (q Query) WithMultipartBody(fields url.Values, fileField, filename string, r io.Reader) Query {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	for k, values := range fields {
+		for _, v := range values {
+			err := writer.WriteField(k, v)
+			checkErr(err)
+		}
+	}
+
+	part, err := writer.CreateFormFile(fileField, filename)
+	checkErr(err)
+	_, err = io.Copy(part, r)
+	checkErr(err)
+	err = writer.Close()
+	checkErr(err)
+
+	q.body = body.Bytes()
+	q.contentType = writer.FormDataContentType()
+	return q
+}
+
+func (q Query) WithHeader(key, value string) Query {
+	headers := http.Header{}
+	for k, v := range q.headers {
+		headers[k] = v
+	}
+	headers.Set(key, value)
+	q.headers = headers
+	return q
+}
+
+func (q Query) WithContext(ctx context.Context) Query {
+	q.ctx = ctx
+	return q
+}
+
 func (q Query) Inspect(resp *APIResponse) Query {
 	q.rawAPIResponse = resp
 	return q
@@ -133,6 +177,14 @@ func (q Query) DoRequest() (resp *http.Response, err error) {
 		req.Header.Add(CTHEADER, q.contentType)
 	}
 
+	for k, v := range q.headers {
+		req.Header[k] = v
+	}
+
+	if q.ctx != nil {
+		req = req.WithContext(q.ctx)
+	}
+
 	resp, err = q.Client.http.Do(req)
 	checkErr(err)
 
@@ -169,9 +221,34 @@ func checkHTTPError(resp *http.Response) error {
 	return nil
 }
 
+const API_ERROR_PATH_NOT_FOUND = "path_not_found"
+const API_ERROR_DESTINATION_CONFLICT = "destination_conflict"
+
+// APIError is returned when the box answers with success set to false.
+type APIError struct {
+	Code string
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return e.Msg
+}
+
+// Is makes errors.Is(err, os.ErrNotExist) hold for missing paths and
+// errors.Is(err, os.ErrExist) for conflicting destinations.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case os.ErrNotExist:
+		return e.Code == API_ERROR_PATH_NOT_FOUND
+	case os.ErrExist:
+		return e.Code == API_ERROR_DESTINATION_CONFLICT
+	}
+	return false
+}
+
 func checkAPIError(resp *APIResponse) error {
 	if !resp.Success {
-		return errors.New(resp.Msg)
+		return &APIError{Code: resp.ErrorCode, Msg: resp.Msg}
 	}
 	return nil
 }
@@ -203,17 +280,24 @@ func (q *Query) makeUrl(proto string, urlmap map[string]string) *url.URL {
 	ep := q.Endpoint.Url
 	buf := new(bytes.Buffer)
 	if urlmap != nil {
-		ptmpl, err := tmpl.Parse(q.Endpoint.Url)
+		// parse a fresh template each time, a shared one is not safe for
+		// concurrent queries
+		ptmpl, err := template.New("url").Parse(q.Endpoint.Url)
 		checkErr(err)
 		err = ptmpl.Execute(buf, urlmap)
 		checkErr(err)
 		ep = buf.String()
 	}
-	return &url.URL{
+	u := &url.URL{
 		Scheme: proto,
 		Host:   fmt.Sprintf("%s:%d", q.Client.session.RemoteAPIDomain, q.Client.session.RemoteHTTPSPort),
 		Path:   fmt.Sprintf("%sv%d/%s", q.Client.session.APIBaseURL, q.Client.session.Version, ep),
 	}
+	// url params may be path escaped, e.g. tracker urls holding slashes
+	if unescaped, err := url.PathUnescape(u.Path); err == nil && unescaped != u.Path {
+		u.Path, u.RawPath = unescaped, u.Path
+	}
+	return u
 }
 
 func stringify(in map[string]interface{}) (values url.Values) {
@@ -230,6 +314,8 @@ func stringify(in map[string]interface{}) (values url.Values) {
 			vs = strconv.FormatFloat(rv.Float(), 'f', 4, 32)
 		case float64:
 			vs = strconv.FormatFloat(rv.Float(), 'f', 4, 64)
+		case json.Number:
+			vs = rv.String()
 		case []byte:
 			vs = string(rv.Bytes())
 		case string:
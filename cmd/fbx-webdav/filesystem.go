@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/jsurloppe/fbxapi"
+	"golang.org/x/net/webdav"
+)
+
+// fsClient is the part of *fbxapi.Client used by freeboxFS.
+type fsClient interface {
+	Info(path string) (*fbxapi.FileInfo, error)
+	Ls(path string, onlyFolder, countSubFolder, removeHidden bool) ([]fbxapi.FileInfo, error)
+	Mkdir(parent, dirname string) (string, error)
+	Open(path string) (*fbxapi.RemoteFile, error)
+	Dl(path string) (*http.Response, error)
+	UploadReader(ctx context.Context, r io.Reader, size int64, destDir, name string, opts *fbxapi.UploadOptions) error
+	Remove(paths ...string) (*fbxapi.FSTask, error)
+	Move(paths []string, dstDir, mode string) (*fbxapi.FSTask, error)
+	Rename(path, newName string) (*fbxapi.FileInfo, error)
+	WaitTask(ctx context.Context, id int) (*fbxapi.FSTask, error)
+}
+
+// freeboxFS maps the webdav operations to the Freebox file system API, all
+// the names being relative to root.
+type freeboxFS struct {
+	client fsClient
+	root   string
+}
+
+// notExist turns the missing path errors of the box into the ones webdav
+// recognizes with os.IsNotExist.
+func notExist(op, name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return err
+}
+
+func (fsys *freeboxFS) remote(name string) string {
+	return path.Join(fsys.root, path.Clean("/"+name))
+}
+
+func (fsys *freeboxFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	parent, dirname := path.Split(fsys.remote(name))
+	_, err := fsys.client.Mkdir(parent, dirname)
+	return notExist("mkdir", name, err)
+}
+
+func (fsys *freeboxFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	remote := fsys.remote(name)
+
+	info, err := fsys.client.Info(remote)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	exists := err == nil
+
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		if !exists {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		if info.Type == fbxapi.FILE_TYPE_DIR {
+			return &dir{fsys: fsys, remote: remote, info: info}, nil
+		}
+		f, err := fsys.client.Open(remote)
+		if err != nil {
+			return nil, err
+		}
+		return &file{RemoteFile: f}, nil
+	}
+
+	if exists && flag&os.O_EXCL != 0 {
+		return nil, os.ErrExist
+	}
+	if !exists && flag&os.O_CREATE == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if exists && info.Type == fbxapi.FILE_TYPE_DIR {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	return newWriteFile(ctx, fsys, remote, exists && flag&os.O_TRUNC == 0)
+}
+
+func (fsys *freeboxFS) wait(ctx context.Context, task *fbxapi.FSTask, err error) error {
+	if err != nil {
+		return err
+	}
+	task, err = fsys.client.WaitTask(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if !task.Succeeded() {
+		return errors.New(task.Error)
+	}
+	return nil
+}
+
+func (fsys *freeboxFS) RemoveAll(ctx context.Context, name string) error {
+	remote := fsys.remote(name)
+	if remote == path.Clean(fsys.root) {
+		return os.ErrPermission
+	}
+
+	task, err := fsys.client.Remove(remote)
+	return fsys.wait(ctx, task, err)
+}
+
+// Rename renames the file in place when it stays in its folder. Otherwise it
+// first takes a unique temporary name, so the move can not clash with an
+// unrelated file of the destination folder, then gets its final name there.
+// webdav already removed the destination when Overwrite allowed it, so an
+// existing file is never replaced.
+func (fsys *freeboxFS) Rename(ctx context.Context, oldName, newName string) error {
+	oldRemote, newRemote := fsys.remote(oldName), fsys.remote(newName)
+	oldDir, oldBase := path.Split(oldRemote)
+	newDir, newBase := path.Split(newRemote)
+
+	if oldDir == newDir {
+		if oldBase == newBase {
+			return nil
+		}
+		_, err := fsys.client.Rename(oldRemote, newBase)
+		return notExist("rename", oldName, err)
+	}
+
+	movedBase := oldBase
+	if oldBase != newBase {
+		movedBase = fmt.Sprintf(".fbx-webdav-%d-%s", time.Now().UnixNano(), newBase)
+		if _, err := fsys.client.Rename(oldRemote, movedBase); err != nil {
+			return notExist("rename", oldName, err)
+		}
+		oldRemote = path.Join(oldDir, movedBase)
+	}
+
+	task, err := fsys.client.Move([]string{oldRemote}, newDir, fbxapi.FS_CONFLICT_SKIP)
+	err = fsys.wait(ctx, task, err)
+	if err == nil {
+		// the skip mode succeeds without moving anything on a conflict
+		if _, infoErr := fsys.client.Info(oldRemote); infoErr == nil {
+			err = &os.PathError{Op: "rename", Path: newName, Err: os.ErrExist}
+		}
+	}
+	if err != nil {
+		if movedBase != oldBase {
+			fsys.client.Rename(oldRemote, oldBase)
+		}
+		return err
+	}
+
+	if movedBase != newBase {
+		_, err = fsys.client.Rename(path.Join(newDir, movedBase), newBase)
+	}
+	return err
+}
+
+func (fsys *freeboxFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := fsys.client.Info(fsys.remote(name))
+	if err != nil {
+		return nil, notExist("stat", name, err)
+	}
+	return info.FSFileInfo(), nil
+}
+
+// file serves reads with ranged requests on the dl endpoint.
+type file struct {
+	*fbxapi.RemoteFile
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	info, err := f.RemoteFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return info.FSFileInfo(), nil
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+type dir struct {
+	fsys    *freeboxFS
+	remote  string
+	info    *fbxapi.FileInfo
+	entries []os.FileInfo
+	listed  bool
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (d *dir) Stat() (os.FileInfo, error) {
+	return d.info.FSFileInfo(), nil
+}
+
+func (d *dir) Readdir(count int) (entries []os.FileInfo, err error) {
+	if !d.listed {
+		infos, err := d.fsys.client.Ls(d.remote, false, false, false)
+		if err != nil {
+			return nil, notExist("readdir", d.remote, err)
+		}
+		for i := range infos {
+			if infos[i].Name != "." && infos[i].Name != ".." {
+				d.entries = append(d.entries, infos[i].FSFileInfo())
+			}
+		}
+		d.listed = true
+	}
+
+	if count <= 0 {
+		entries, d.entries = d.entries, nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries, d.entries = d.entries[:count], d.entries[count:]
+	return entries, nil
+}
+
+// writeFile buffers the content in a local temporary file, the upload needs
+// the final size and only happens on Close.
+type writeFile struct {
+	*os.File
+	ctx    context.Context
+	fsys   *freeboxFS
+	remote string
+}
+
+func newWriteFile(ctx context.Context, fsys *freeboxFS, remote string, keepContent bool) (wf *writeFile, err error) {
+	tmp, err := ioutil.TempFile("", "fbx-webdav")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(tmp.Name())
+
+	if keepContent {
+		resp, err := fsys.client.Dl(remote)
+		if err != nil {
+			tmp.Close()
+			return nil, err
+		}
+		_, err = io.Copy(tmp, resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			_, err = tmp.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			tmp.Close()
+			return nil, err
+		}
+	}
+
+	return &writeFile{File: tmp, ctx: ctx, fsys: fsys, remote: remote}, nil
+}
+
+func (wf *writeFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (wf *writeFile) Stat() (os.FileInfo, error) {
+	fi, err := wf.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	info := &fbxapi.FileInfo{
+		Name:         path.Base(wf.remote),
+		Type:         fbxapi.FILE_TYPE_FILE,
+		Size:         int(fi.Size()),
+		Modification: fbxapi.NewTimestamp(time.Now()),
+	}
+	return info.FSFileInfo(), nil
+}
+
+func (wf *writeFile) Close() error {
+	defer wf.File.Close()
+
+	fi, err := wf.File.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := wf.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	destDir, name := path.Split(wf.remote)
+	opts := &fbxapi.UploadOptions{Conflict: fbxapi.UPLOAD_CONFLICT_OVERWRITE}
+	return wf.fsys.client.UploadReader(wf.ctx, wf.File, fi.Size(), destDir, name, opts)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/jsurloppe/fbxapi"
+	"golang.org/x/net/webdav"
+)
+
+// stubClient keeps an in memory tree, folders being listed with a trailing
+// slash in newStubClient.
+type stubClient struct {
+	files map[string]*fbxapi.FileInfo
+}
+
+func newStubClient(paths ...string) *stubClient {
+	s := &stubClient{files: map[string]*fbxapi.FileInfo{}}
+	for _, p := range paths {
+		info := &fbxapi.FileInfo{Type: fbxapi.FILE_TYPE_FILE, Size: len(p)}
+		if strings.HasSuffix(p, "/") {
+			info.Type = fbxapi.FILE_TYPE_DIR
+		}
+		s.add(path.Clean(p), info)
+	}
+	return s
+}
+
+func (s *stubClient) add(p string, info *fbxapi.FileInfo) {
+	info.Path = fbxapi.FilePath(p)
+	info.Name = path.Base(p)
+	s.files[p] = info
+}
+
+// move renames src and everything below it to dst.
+func (s *stubClient) move(src, dst string) {
+	for p, info := range s.files {
+		if p == src || strings.HasPrefix(p, src+"/") {
+			delete(s.files, p)
+			s.add(dst+strings.TrimPrefix(p, src), info)
+		}
+	}
+}
+
+func (s *stubClient) Info(p string) (*fbxapi.FileInfo, error) {
+	info, ok := s.files[p]
+	if !ok {
+		return nil, &fbxapi.APIError{Code: fbxapi.API_ERROR_PATH_NOT_FOUND, Msg: "not found"}
+	}
+	return info, nil
+}
+
+func (s *stubClient) Ls(dir string, onlyFolder, countSubFolder, removeHidden bool) (infos []fbxapi.FileInfo, err error) {
+	if _, err = s.Info(dir); err != nil {
+		return
+	}
+	for p, info := range s.files {
+		if path.Dir(p) == dir && p != dir {
+			infos = append(infos, *info)
+		}
+	}
+	return
+}
+
+func (s *stubClient) Mkdir(parent, dirname string) (string, error) {
+	if _, err := s.Info(path.Clean(parent)); err != nil {
+		return "", err
+	}
+	p := path.Join(parent, dirname)
+	s.add(p, &fbxapi.FileInfo{Type: fbxapi.FILE_TYPE_DIR})
+	return p, nil
+}
+
+func (s *stubClient) Open(p string) (*fbxapi.RemoteFile, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubClient) Dl(p string) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubClient) UploadReader(ctx context.Context, r io.Reader, size int64, destDir, name string, opts *fbxapi.UploadOptions) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.add(path.Join(destDir, name), &fbxapi.FileInfo{Type: fbxapi.FILE_TYPE_FILE, Size: len(content)})
+	return nil
+}
+
+func (s *stubClient) Remove(paths ...string) (*fbxapi.FSTask, error) {
+	for _, src := range paths {
+		for p := range s.files {
+			if p == src || strings.HasPrefix(p, src+"/") {
+				delete(s.files, p)
+			}
+		}
+	}
+	return &fbxapi.FSTask{ID: 1}, nil
+}
+
+func (s *stubClient) Move(paths []string, dstDir, mode string) (*fbxapi.FSTask, error) {
+	for _, src := range paths {
+		dst := path.Join(dstDir, path.Base(src))
+		if _, exists := s.files[dst]; exists {
+			if mode != fbxapi.FS_CONFLICT_OVERWRITE {
+				continue
+			}
+			s.Remove(dst)
+		}
+		s.move(src, dst)
+	}
+	return &fbxapi.FSTask{ID: 1}, nil
+}
+
+func (s *stubClient) Rename(src, newName string) (*fbxapi.FileInfo, error) {
+	if _, err := s.Info(src); err != nil {
+		return nil, err
+	}
+	dst := path.Join(path.Dir(src), newName)
+	if _, exists := s.files[dst]; exists {
+		return nil, &fbxapi.APIError{Code: fbxapi.API_ERROR_DESTINATION_CONFLICT, Msg: "conflict"}
+	}
+	s.move(src, dst)
+	return s.files[dst], nil
+}
+
+func (s *stubClient) WaitTask(ctx context.Context, id int) (*fbxapi.FSTask, error) {
+	return &fbxapi.FSTask{ID: id, State: fbxapi.FS_TASK_STATE_DONE}, nil
+}
+
+func serve(handler http.Handler, method, target string, headers map[string]string) int {
+	req := httptest.NewRequest(method, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestMove(t *testing.T) {
+	client := newStubClient("/root/", "/root/a/", "/root/a/x.txt", "/root/b/", "/root/b/x.txt", "/root/b/z.txt")
+	moved, kept := client.files["/root/a/x.txt"], client.files["/root/b/x.txt"]
+
+	handler := &webdav.Handler{
+		FileSystem: &freeboxFS{client: client, root: "/root"},
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	code := serve(handler, "MOVE", "/a/x.txt", map[string]string{
+		"Destination": "http://example.com/b/y.txt",
+		"Overwrite":   "F",
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d", http.StatusCreated, code)
+	}
+	if client.files["/root/b/y.txt"] != moved {
+		t.Fatal("file not moved to its new name")
+	}
+	if client.files["/root/b/x.txt"] != kept {
+		t.Fatal("file of the same name in the destination folder replaced")
+	}
+	if _, ok := client.files["/root/a/x.txt"]; ok {
+		t.Fatal("source still exists")
+	}
+	for p := range client.files {
+		if strings.Contains(p, ".fbx-webdav-") {
+			t.Fatalf("temporary name %s left", p)
+		}
+	}
+
+	code = serve(handler, "MOVE", "/b/y.txt", map[string]string{
+		"Destination": "http://example.com/b/z.txt",
+		"Overwrite":   "F",
+	})
+	if code != http.StatusPreconditionFailed {
+		t.Fatalf("expected %d on existing destination, got %d", http.StatusPreconditionFailed, code)
+	}
+}
+
+func TestMkcol(t *testing.T) {
+	client := newStubClient("/root/")
+	handler := &webdav.Handler{
+		FileSystem: &freeboxFS{client: client, root: "/root"},
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	if code := serve(handler, "MKCOL", "/new", nil); code != http.StatusCreated {
+		t.Fatalf("expected %d, got %d", http.StatusCreated, code)
+	}
+	if _, ok := client.files["/root/new"]; !ok {
+		t.Fatal("folder not created")
+	}
+	if code := serve(handler, "MKCOL", "/missing/new", nil); code != http.StatusConflict {
+		t.Fatalf("expected %d with a missing parent, got %d", http.StatusConflict, code)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	handler := &webdav.Handler{
+		FileSystem: &freeboxFS{client: newStubClient("/root/"), root: "/root"},
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	for _, method := range []string{"GET", "PROPFIND", "DELETE"} {
+		if code := serve(handler, method, "/missing", nil); code != http.StatusNotFound {
+			t.Errorf("%s: expected %d, got %d", method, http.StatusNotFound, code)
+		}
+	}
+}
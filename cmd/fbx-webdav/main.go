@@ -0,0 +1,62 @@
+// Command fbx-webdav exposes the Freebox storage as a local WebDAV server.
+//
+// The application must have been registered beforehand, its token is read
+// from -token or from the file given by -token-file:
+//
+//	fbx-webdav -app-id com.example.app -token-file ~/.fbx-token -root "/Disque dur"
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jsurloppe/fbxapi"
+	"golang.org/x/net/webdav"
+)
+
+func main() {
+	host := flag.String("host", "mafreebox.freebox.fr", "Freebox host")
+	port := flag.Int("port", 443, "Freebox HTTPS port")
+	appID := flag.String("app-id", "com.github.jsurloppe.fbxapi", "registered application id")
+	token := flag.String("token", "", "application token")
+	tokenFile := flag.String("token-file", "", "file holding the application token")
+	root := flag.String("root", "/", "Freebox folder served as the WebDAV root")
+	listen := flag.String("listen", "127.0.0.1:8080", "address to listen on")
+	flag.Parse()
+
+	if *token == "" && *tokenFile != "" {
+		content, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*token = strings.TrimSpace(string(content))
+	}
+
+	app := &fbxapi.App{
+		ID:    *appID,
+		Token: *token,
+	}
+
+	fb := fbxapi.NewFreebox(*host, *port)
+	client, err := fb.OpenSession(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Logout()
+
+	handler := &webdav.Handler{
+		FileSystem: &freeboxFS{client: client, root: *root},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+
+	log.Printf("serving %s on http://%s/", *root, *listen)
+	log.Fatal(http.ListenAndServe(*listen, handler))
+}
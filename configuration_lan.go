@@ -4,6 +4,52 @@ import (
 	"net"
 )
 
+// LanHostType is the kind of device of a LanHost, as guessed by the box or
+// set by the user.
+type LanHostType string
+
+const LAN_HOST_TYPE_WORKSTATION LanHostType = "workstation"
+const LAN_HOST_TYPE_LAPTOP LanHostType = "laptop"
+const LAN_HOST_TYPE_SMARTPHONE LanHostType = "smartphone"
+const LAN_HOST_TYPE_TABLET LanHostType = "tablet"
+const LAN_HOST_TYPE_PRINTER LanHostType = "printer"
+const LAN_HOST_TYPE_VG_CONSOLE LanHostType = "vg_console"
+const LAN_HOST_TYPE_TELEVISION LanHostType = "television"
+const LAN_HOST_TYPE_NAS LanHostType = "nas"
+const LAN_HOST_TYPE_IP_CAMERA LanHostType = "ip_camera"
+const LAN_HOST_TYPE_IP_PHONE LanHostType = "ip_phone"
+const LAN_HOST_TYPE_FREEBOX_PLAYER LanHostType = "freebox_player"
+const LAN_HOST_TYPE_FREEBOX_HD LanHostType = "freebox_hd"
+const LAN_HOST_TYPE_FREEBOX_CRYSTAL LanHostType = "freebox_crystal"
+const LAN_HOST_TYPE_FREEBOX_MINI LanHostType = "freebox_mini"
+const LAN_HOST_TYPE_FREEBOX_DELTA LanHostType = "freebox_delta"
+const LAN_HOST_TYPE_FREEBOX_ONE LanHostType = "freebox_one"
+const LAN_HOST_TYPE_FREEBOX_WIFI LanHostType = "freebox_wifi"
+const LAN_HOST_TYPE_FREEBOX_POP LanHostType = "freebox_pop"
+const LAN_HOST_TYPE_NETWORKING_DEVICE LanHostType = "networking_device"
+const LAN_HOST_TYPE_MULTIMEDIA_DEVICE LanHostType = "multimedia_device"
+const LAN_HOST_TYPE_CAR LanHostType = "car"
+const LAN_HOST_TYPE_OTHER LanHostType = "other"
+
+func (t LanHostType) String() string {
+	return string(t)
+}
+
+func (t LanHostType) Known() bool {
+	switch t {
+	case LAN_HOST_TYPE_WORKSTATION, LAN_HOST_TYPE_LAPTOP, LAN_HOST_TYPE_SMARTPHONE,
+		LAN_HOST_TYPE_TABLET, LAN_HOST_TYPE_PRINTER, LAN_HOST_TYPE_VG_CONSOLE,
+		LAN_HOST_TYPE_TELEVISION, LAN_HOST_TYPE_NAS, LAN_HOST_TYPE_IP_CAMERA,
+		LAN_HOST_TYPE_IP_PHONE, LAN_HOST_TYPE_FREEBOX_PLAYER, LAN_HOST_TYPE_FREEBOX_HD,
+		LAN_HOST_TYPE_FREEBOX_CRYSTAL, LAN_HOST_TYPE_FREEBOX_MINI, LAN_HOST_TYPE_FREEBOX_DELTA,
+		LAN_HOST_TYPE_FREEBOX_ONE, LAN_HOST_TYPE_FREEBOX_WIFI, LAN_HOST_TYPE_FREEBOX_POP,
+		LAN_HOST_TYPE_NETWORKING_DEVICE, LAN_HOST_TYPE_MULTIMEDIA_DEVICE, LAN_HOST_TYPE_CAR,
+		LAN_HOST_TYPE_OTHER:
+		return true
+	}
+	return false
+}
+
 type ReqHost struct {
 	ID          string `json:"id"`
 	PrimaryName string `json:"primary_name"`
@@ -39,26 +85,26 @@ type LanHostName struct {
 }
 
 type LanHostL3Connectivity struct {
-	Addr              string `json:"addr"`
-	Af                string `json:"af"`
-	Active            bool   `json:"active"`
-	Reachable         bool   `json:"reachable"`
-	LastActivity      int    `json:"last_activity"`
-	LastTimeReachable int    `json:"last_time_reachable"`
+	Addr              string    `json:"addr"`
+	Af                string    `json:"af"`
+	Active            bool      `json:"active"`
+	Reachable         bool      `json:"reachable"`
+	LastActivity      Timestamp `json:"last_activity"`
+	LastTimeReachable Timestamp `json:"last_time_reachable"`
 }
 
 type LanHost struct {
 	ID                string                  `json:"id"`
 	PrimaryName       string                  `json:"primary_name"`
-	HostType          string                  `json:"host_type"`
+	HostType          LanHostType             `json:"host_type"`
 	PrimaryNameManual bool                    `json:"primary_name_manual"`
 	L2Ident           LanHostL2Ident          `json:"l2ident"`
 	VendorName        string                  `json:"vendor_name"`
 	Persistent        bool                    `json:"persistent"`
 	Reachable         bool                    `json:"reachable"`
-	LastTimeReachable int                     `json:"last_time_reachable"`
+	LastTimeReachable Timestamp               `json:"last_time_reachable"`
 	Active            bool                    `json:"active"`
-	LastActivity      int                     `json:"last_activity"`
+	LastActivity      Timestamp               `json:"last_activity"`
 	Names             []LanHostName           `json:"names"`
 	L3Connectivities  []LanHostL3Connectivity `json:"l3connectivities"`
 	Interface         string                  `json:"interface"` // not documented
@@ -1,30 +1,76 @@
 package fbxapi
 
+// ConnectionState is the state of the internet link.
+type ConnectionState string
+
+const CONNECTION_STATE_GOING_UP ConnectionState = "going_up"
+const CONNECTION_STATE_UP ConnectionState = "up"
+const CONNECTION_STATE_GOING_DOWN ConnectionState = "going_down"
+const CONNECTION_STATE_DOWN ConnectionState = "down"
+
+func (s ConnectionState) String() string {
+	return string(s)
+}
+
+func (s ConnectionState) Known() bool {
+	switch s {
+	case CONNECTION_STATE_GOING_UP, CONNECTION_STATE_UP, CONNECTION_STATE_GOING_DOWN, CONNECTION_STATE_DOWN:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the link is settled, up or down, and not
+// transitioning.
+func (s ConnectionState) IsTerminal() bool {
+	return s == CONNECTION_STATE_UP || s == CONNECTION_STATE_DOWN
+}
+
+// ConnectionMedia is the physical medium of the internet link.
+type ConnectionMedia string
+
+const CONNECTION_MEDIA_FTTH ConnectionMedia = "ftth"
+const CONNECTION_MEDIA_ETHERNET ConnectionMedia = "ethernet"
+const CONNECTION_MEDIA_XDSL ConnectionMedia = "xdsl"
+const CONNECTION_MEDIA_BACKUP_4G ConnectionMedia = "backup_4g"
+
+func (m ConnectionMedia) String() string {
+	return string(m)
+}
+
+func (m ConnectionMedia) Known() bool {
+	switch m {
+	case CONNECTION_MEDIA_FTTH, CONNECTION_MEDIA_ETHERNET, CONNECTION_MEDIA_XDSL, CONNECTION_MEDIA_BACKUP_4G:
+		return true
+	}
+	return false
+}
+
 type ConnectionStatus struct {
-	State         string `json:"state"`
-	Type          string `json:"type"`
-	Media         string `json:"media"`
-	Ipv4          string `json:"ipv4"`
-	Ipv6          string `json:"ipv6"`
-	RateUp        int    `json:"rate_up"`
-	RateDown      int    `json:"rate_down"`
-	BandwidthUp   int    `json:"bandwidth_up"`
-	BandwidthDown int    `json:"bandwidth_down"`
-	BytesUp       int    `json:"bytes_up"`
-	BytesDown     int    `json:"bytes_down"`
-	Ipv4PortRange [2]int `json:"ipv4_port_range"`
+	State         ConnectionState `json:"state"`
+	Type          string          `json:"type"`
+	Media         ConnectionMedia `json:"media"`
+	Ipv4          string          `json:"ipv4"`
+	Ipv6          string          `json:"ipv6"`
+	RateUp        int             `json:"rate_up"`
+	RateDown      int             `json:"rate_down"`
+	BandwidthUp   int             `json:"bandwidth_up"`
+	BandwidthDown int             `json:"bandwidth_down"`
+	BytesUp       int             `json:"bytes_up"`
+	BytesDown     int             `json:"bytes_down"`
+	Ipv4PortRange [2]int          `json:"ipv4_port_range"`
 }
 
 // Undocumented
 type ConnectionLog struct {
-	State         string `json:"state"`
-	Type          string `json:"type"`
-	BandwidthDown int    `json:"bw_down,omitempty"`
-	BandwidthUp   int    `json:"bw_up,omitempty"`
-	Link          string `json:"link,omitempty"`
-	ID            int    `json:"id"`
-	Date          int    `json:"date"`
-	Conn          string `json:"conn,omitempty"`
+	State         string    `json:"state"`
+	Type          string    `json:"type"`
+	BandwidthDown int       `json:"bw_down,omitempty"`
+	BandwidthUp   int       `json:"bw_up,omitempty"`
+	Link          string    `json:"link,omitempty"`
+	ID            int       `json:"id"`
+	Date          Timestamp `json:"date"`
+	Conn          string    `json:"conn,omitempty"`
 }
 
 var ConnectionEP = &Endpoint{
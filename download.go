@@ -1,44 +1,167 @@
 package fbxapi
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// DOWNLOAD_POLL_INTERVAL is the delay between two download states in
+// WaitDownload.
+const DOWNLOAD_POLL_INTERVAL = time.Second
+
+// DownloadStatus is the state of a download task.
+type DownloadStatus string
+
+const DOWNLOAD_STATUS_STOPPED DownloadStatus = "stopped"
+const DOWNLOAD_STATUS_QUEUED DownloadStatus = "queued"
+const DOWNLOAD_STATUS_STARTING DownloadStatus = "starting"
+const DOWNLOAD_STATUS_DOWNLOADING DownloadStatus = "downloading"
+const DOWNLOAD_STATUS_STOPPING DownloadStatus = "stopping"
+const DOWNLOAD_STATUS_ERROR DownloadStatus = "error"
+const DOWNLOAD_STATUS_DONE DownloadStatus = "done"
+const DOWNLOAD_STATUS_CHECKING DownloadStatus = "checking"
+const DOWNLOAD_STATUS_REPAIRING DownloadStatus = "repairing"
+const DOWNLOAD_STATUS_EXTRACTING DownloadStatus = "extracting"
+const DOWNLOAD_STATUS_SEEDING DownloadStatus = "seeding"
+const DOWNLOAD_STATUS_RETRY DownloadStatus = "retry"
+
+func (s DownloadStatus) String() string {
+	return string(s)
+}
+
+func (s DownloadStatus) Known() bool {
+	switch s {
+	case DOWNLOAD_STATUS_STOPPED, DOWNLOAD_STATUS_QUEUED, DOWNLOAD_STATUS_STARTING,
+		DOWNLOAD_STATUS_DOWNLOADING, DOWNLOAD_STATUS_STOPPING, DOWNLOAD_STATUS_ERROR,
+		DOWNLOAD_STATUS_DONE, DOWNLOAD_STATUS_CHECKING, DOWNLOAD_STATUS_REPAIRING,
+		DOWNLOAD_STATUS_EXTRACTING, DOWNLOAD_STATUS_SEEDING, DOWNLOAD_STATUS_RETRY:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the transfer is over, either failed or
+// complete. A seeding torrent is complete even if it still uploads.
+func (s DownloadStatus) IsTerminal() bool {
+	switch s {
+	case DOWNLOAD_STATUS_ERROR, DOWNLOAD_STATUS_DONE, DOWNLOAD_STATUS_SEEDING:
+		return true
+	}
+	return false
+}
+
+// DownloadIOPriority is the disk access priority of a download task.
+type DownloadIOPriority string
+
+const DOWNLOAD_IO_PRIORITY_LOW DownloadIOPriority = "low"
+const DOWNLOAD_IO_PRIORITY_NORMAL DownloadIOPriority = "normal"
+const DOWNLOAD_IO_PRIORITY_HIGH DownloadIOPriority = "high"
+
+func (p DownloadIOPriority) String() string {
+	return string(p)
+}
+
+func (p DownloadIOPriority) Known() bool {
+	switch p {
+	case DOWNLOAD_IO_PRIORITY_LOW, DOWNLOAD_IO_PRIORITY_NORMAL, DOWNLOAD_IO_PRIORITY_HIGH:
+		return true
+	}
+	return false
+}
+
 type Download struct {
-	ID              int    `json:"id"`
-	Type            string `json:"type"`
-	Name            string `json:"name"`
-	Status          string `json:"status"`
-	Size            int    `json:"size"`
-	QueuePos        int    `json:"queue_pos"`
-	IOPriority      string `json:"io_priority"`
-	TXBytes         int    `json:"tx_bytes"`
-	RXBytes         int    `json:"rx_bytes"`
-	TXRate          int    `json:"tx_rate"`
-	RXRate          int    `json:"rx_rate"`
-	TXPct           int    `json:"tx_pct"`
-	RXPct           int    `json:"rx_pct"`
-	Error           string `json:"error"`
-	CreatedTS       int    `json:"created_ts"`
-	ETA             int    `json:"eta"`
-	DownloadDir     string `json:"download_dir"`
-	StopRatio       int    `json:"stop_ratio"`
-	ArchivePassword string `json:"stop_ratio"`
-	InfoHash        string `json:"info_hash"`
-	PieceLength     int    `json:"piece_length"`
+	ID              int                `json:"id"`
+	Type            string             `json:"type"`
+	Name            string             `json:"name"`
+	Status          DownloadStatus     `json:"status"`
+	Size            int                `json:"size"`
+	QueuePos        int                `json:"queue_pos"`
+	IOPriority      DownloadIOPriority `json:"io_priority"`
+	TXBytes         int                `json:"tx_bytes"`
+	RXBytes         int                `json:"rx_bytes"`
+	TXRate          int                `json:"tx_rate"`
+	RXRate          int                `json:"rx_rate"`
+	TXPct           int                `json:"tx_pct"`
+	RXPct           int                `json:"rx_pct"`
+	Error           string             `json:"error"`
+	CreatedTS       Timestamp          `json:"created_ts"`
+	ETA             Duration           `json:"eta"`
+	DownloadDir     FilePath           `json:"download_dir"`
+	StopRatio       int                `json:"stop_ratio"`
+	ArchivePassword string             `json:"archive_password"`
+	InfoHash        string             `json:"info_hash"`
+	PieceLength     int                `json:"piece_length"`
 }
 
 type DownloadReq struct {
-	DownloadUrl     string `json:"download_url,omitempty"`
-	DownloadUrlList string `json:"download_url_list,omitempty"`
-	DownloadDir     string `json:"download_dir"`
-	Recursive       bool   `json:"recursive,omitempty"`
-	Username        string `json:"username,omitempty"`
-	Password        string `json:"password,omitempty"`
-	ArchivePassword string `json:"archive_password,omitempty"`
-	Cookies         string `json:"cookies,omitempty"`
+	DownloadUrl     string   `json:"download_url,omitempty"`
+	DownloadUrlList string   `json:"download_url_list,omitempty"`
+	DownloadDir     FilePath `json:"download_dir,omitempty"`
+	Recursive       bool     `json:"recursive,omitempty"`
+	Username        string   `json:"username,omitempty"`
+	Password        string   `json:"password,omitempty"`
+	ArchivePassword string   `json:"archive_password,omitempty"`
+	Cookies         string   `json:"cookies,omitempty"`
+}
+
+// AddDownloadFileOptions tunes AddDownloadFile, a nil value uses the box
+// default download folder.
+type AddDownloadFileOptions struct {
+	DownloadDir     string
+	ArchivePassword string
+	// Paused stops the task right after it is added. The box has no way to
+	// add a stopped task, a few bytes may be transferred in between.
+	Paused bool
+}
+
+// DownloadProgress is reported by WaitDownload, RXPct is in hundredths of
+// percent.
+type DownloadProgress struct {
+	ID     int
+	Status DownloadStatus
+	RXPct  int
+	RXRate int
+	ETA    Duration
+}
+
+// DownloadResult is the final state of a download.
+type DownloadResult struct {
+	Download *Download
+	// Path is where the download was written on the box.
+	Path string
+}
+
+// DownloadError is returned by WaitDownload when the download ends in
+// DOWNLOAD_STATUS_ERROR, Code being the Download.Error value. DownloadLog
+// usually tells more.
+type DownloadError struct {
+	ID   int
+	Code string
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download %d failed: %s", e.ID, e.Code)
 }
 
 type DownloadTask struct {
 	ID int `json:"id"`
 }
 
+// DownloadUpdateReq holds the download fields which can be changed, zero
+// values are left untouched. Status only accepts DOWNLOAD_STATUS_STOPPED,
+// DOWNLOAD_STATUS_DOWNLOADING, DOWNLOAD_STATUS_QUEUED and
+// DOWNLOAD_STATUS_RETRY.
+type DownloadUpdateReq struct {
+	Status     DownloadStatus     `json:"status,omitempty"`
+	IOPriority DownloadIOPriority `json:"io_priority,omitempty"`
+	QueuePos   int                `json:"queue_pos,omitempty"`
+}
+
 // DownloadsEP endpoint definition
 // Output: []Download
 var DownloadsEP = &Endpoint{
@@ -46,6 +169,20 @@ var DownloadsEP = &Endpoint{
 	Url:  "downloads/",
 }
 
+// DownloadEP endpoint definition
+// Output: Download
+var DownloadEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}",
+}
+
+// UpdateDownloadEP endpoint definition
+// Output: Download
+var UpdateDownloadEP = &Endpoint{
+	Verb: HTTP_METHOD_PUT,
+	Url:  "downloads/{{.id}}",
+}
+
 // DeleteDownloadEP endpoint definition
 var DeleteDownloadEP = &Endpoint{
 	Verb: HTTP_METHOD_DELETE,
@@ -59,8 +196,168 @@ var EraseDownloadEP = &Endpoint{
 }
 
 // AddDownloadEP endpoint definition
-// Output: Download
+// Output: DownloadTask
 var AddDownloadEP = &Endpoint{
 	Verb: HTTP_METHOD_POST,
 	Url:  "downloads/add/",
 }
+
+func (c *Client) ListDownloads() (downloads []Download, err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(DownloadsEP).Do(&downloads)
+	checkErr(err)
+	return
+}
+
+func (c *Client) GetDownload(id int) (download *Download, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	download = new(Download)
+	err = c.Query(DownloadEP).As(params).Do(download)
+	checkErr(err)
+	return
+}
+
+// AddDownload queues the urls of req, the endpoint only accepts a form
+// encoded body. It returns the id of the new task.
+func (c *Client) AddDownload(req *DownloadReq) (id int, err error) {
+	defer panicAttack(&err)
+
+	var task DownloadTask
+	err = c.Query(AddDownloadEP).WithFormBody(req).Do(&task)
+	checkErr(err)
+
+	id = task.ID
+	return
+}
+
+// AddDownloadFile queues the .torrent or .nzb file read from r, filename
+// extension telling the box how to handle it. It returns the id of the new
+// task.
+func (c *Client) AddDownloadFile(ctx context.Context, r io.Reader, filename string, opts *AddDownloadFileOptions) (id int, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &AddDownloadFileOptions{}
+	}
+
+	fields := url.Values{}
+	if opts.DownloadDir != "" {
+		fields.Set("download_dir", EncodePath(opts.DownloadDir))
+	}
+	if opts.ArchivePassword != "" {
+		fields.Set("archive_password", opts.ArchivePassword)
+	}
+
+	var task DownloadTask
+	err = c.Query(AddDownloadEP).WithContext(ctx).
+		WithMultipartBody(fields, "download_file", filename, r).Do(&task)
+	checkErr(err)
+	id = task.ID
+
+	if opts.Paused {
+		_, err = c.UpdateDownload(id, &DownloadUpdateReq{Status: DOWNLOAD_STATUS_STOPPED})
+		checkErr(err)
+	}
+	return
+}
+
+// UpdateDownload stops, resumes or retries a download and changes its
+// priority or position in the queue.
+func (c *Client) UpdateDownload(id int, req *DownloadUpdateReq) (download *Download, err error) {
+	defer panicAttack(&err)
+
+	switch req.Status {
+	case "", DOWNLOAD_STATUS_STOPPED, DOWNLOAD_STATUS_DOWNLOADING, DOWNLOAD_STATUS_QUEUED, DOWNLOAD_STATUS_RETRY:
+	default:
+		checkErr(fmt.Errorf("download status can not be set to %q", req.Status))
+	}
+	if req.IOPriority != "" {
+		err = checkEnum(req.IOPriority, "download io priority")
+		checkErr(err)
+	}
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	download = new(Download)
+	err = c.Query(UpdateDownloadEP).As(params).WithBody(req).Do(download)
+	checkErr(err)
+	return
+}
+
+// DeleteDownload removes the task, the downloaded files are kept.
+func (c *Client) DeleteDownload(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DeleteDownloadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// EraseDownload removes the task along with the downloaded files.
+func (c *Client) EraseDownload(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(EraseDownloadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// WaitDownload polls the download until its status IsTerminal. When progress
+// is not nil, each state is sent on it, the caller must keep reading it. On
+// DOWNLOAD_STATUS_ERROR the result is returned along with a *DownloadError.
+func (c *Client) WaitDownload(ctx context.Context, id int, progress chan<- DownloadProgress) (result *DownloadResult, err error) {
+	defer panicAttack(&err)
+
+	for {
+		download, err := c.GetDownload(id)
+		checkErr(err)
+
+		if progress != nil {
+			state := DownloadProgress{
+				ID:     download.ID,
+				Status: download.Status,
+				RXPct:  download.RXPct,
+				RXRate: download.RXRate,
+				ETA:    download.ETA,
+			}
+			select {
+			case progress <- state:
+			case <-ctx.Done():
+				checkErr(ctx.Err())
+			}
+		}
+
+		if download.Status.IsTerminal() {
+			result = &DownloadResult{
+				Download: download,
+				Path:     path.Join(string(download.DownloadDir), download.Name),
+			}
+			if download.Status == DOWNLOAD_STATUS_ERROR {
+				err = &DownloadError{ID: id, Code: download.Error}
+			}
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			checkErr(ctx.Err())
+		case <-time.After(DOWNLOAD_POLL_INTERVAL):
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package fbxapi
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DownloadTrackerStatus is the announce state of a torrent tracker.
+type DownloadTrackerStatus string
+
+const DOWNLOAD_TRACKER_STATUS_UNKNOWN DownloadTrackerStatus = "unknown"
+const DOWNLOAD_TRACKER_STATUS_WORKING DownloadTrackerStatus = "working"
+const DOWNLOAD_TRACKER_STATUS_ANNOUNCING DownloadTrackerStatus = "announcing"
+const DOWNLOAD_TRACKER_STATUS_ERROR DownloadTrackerStatus = "error"
+const DOWNLOAD_TRACKER_STATUS_DISABLED DownloadTrackerStatus = "disabled"
+
+func (s DownloadTrackerStatus) String() string {
+	return string(s)
+}
+
+func (s DownloadTrackerStatus) Known() bool {
+	switch s {
+	case DOWNLOAD_TRACKER_STATUS_UNKNOWN, DOWNLOAD_TRACKER_STATUS_WORKING, DOWNLOAD_TRACKER_STATUS_ANNOUNCING,
+		DOWNLOAD_TRACKER_STATUS_ERROR, DOWNLOAD_TRACKER_STATUS_DISABLED:
+		return true
+	}
+	return false
+}
+
+// Piece states of DownloadPiecesEP, one character per piece.
+const DOWNLOAD_PIECE_DONE = 'X'
+const DOWNLOAD_PIECE_MISSING = '.'
+const DOWNLOAD_PIECE_DOWNLOADING = '/'
+
+type DownloadTracker struct {
+	Announce     string                `json:"announce"`
+	IsBackup     bool                  `json:"is_backup"`
+	Status       DownloadTrackerStatus `json:"status"`
+	Interval     Duration              `json:"interval"`
+	MinInterval  Duration              `json:"min_interval"`
+	ReannounceIn Duration              `json:"reannounce_in"`
+	Seeders      int                   `json:"seeders"`
+	Leechers     int                   `json:"leechers"`
+	Msg          string                `json:"msg"`
+	IsEnabled    bool                  `json:"is_enabled"`
+}
+
+type DownloadTrackerReq struct {
+	Announce string `json:"announce"`
+}
+
+type DownloadTrackerUpdateReq struct {
+	IsEnabled bool `json:"is_enabled"`
+}
+
+type DownloadPeer struct {
+	Host     string  `json:"host"`
+	Port     int     `json:"port"`
+	Origin   string  `json:"origin"`
+	State    string  `json:"state"`
+	Flags    string  `json:"flags"`
+	Client   string  `json:"client"`
+	Country  string  `json:"country"`
+	RXBytes  int     `json:"rx_bytes"`
+	TXBytes  int     `json:"tx_bytes"`
+	RXRate   int     `json:"rx_rate"`
+	TXRate   int     `json:"tx_rate"`
+	Progress float64 `json:"progress"`
+	Requests int     `json:"requests"`
+}
+
+type DownloadBlacklistEntry struct {
+	Host   string   `json:"host"`
+	Expire Duration `json:"expire"`
+	Global bool     `json:"global"`
+}
+
+// DownloadTrackersEP endpoint definition
+// Output: []DownloadTracker
+var DownloadTrackersEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/trackers",
+}
+
+// AddDownloadTrackerEP endpoint definition
+// Output: nil
+var AddDownloadTrackerEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/{{.id}}/trackers",
+}
+
+// UpdateDownloadTrackerEP endpoint definition
+// Output: nil
+var UpdateDownloadTrackerEP = &Endpoint{
+	Verb: HTTP_METHOD_PUT,
+	Url:  "downloads/{{.id}}/trackers/{{.announce}}",
+}
+
+// DeleteDownloadTrackerEP endpoint definition
+// Output: nil
+var DeleteDownloadTrackerEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "downloads/{{.id}}/trackers/{{.announce}}",
+}
+
+// DownloadPeersEP endpoint definition
+// Output: []DownloadPeer
+var DownloadPeersEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/peers",
+}
+
+// DownloadPiecesEP endpoint definition
+// Output: string
+var DownloadPiecesEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/pieces",
+}
+
+// DownloadBlacklistEP endpoint definition
+// Output: []DownloadBlacklistEntry
+var DownloadBlacklistEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/blacklist",
+}
+
+// EmptyDownloadBlacklistEP endpoint definition
+// Output: nil
+var EmptyDownloadBlacklistEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "downloads/{{.id}}/blacklist/empty",
+}
+
+// AddBlacklistEP endpoint definition
+// Output: DownloadBlacklistEntry
+var AddBlacklistEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/blacklist/",
+}
+
+// PieceBitmap tells which pieces of a torrent are complete.
+type PieceBitmap struct {
+	bits []byte
+	n    int
+}
+
+// NewPieceBitmap decodes the piece states returned by DownloadPiecesEP,
+// only DOWNLOAD_PIECE_DONE pieces are set.
+func NewPieceBitmap(states string) *PieceBitmap {
+	bitmap := &PieceBitmap{
+		bits: make([]byte, (len(states)+7)/8),
+		n:    len(states),
+	}
+	for i := 0; i < len(states); i++ {
+		if states[i] == DOWNLOAD_PIECE_DONE {
+			bitmap.bits[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return bitmap
+}
+
+// Len returns the number of pieces of the torrent.
+func (b *PieceBitmap) Len() int {
+	return b.n
+}
+
+// Has reports whether piece i is complete.
+func (b *PieceBitmap) Has(i int) bool {
+	if i < 0 || i >= b.n {
+		return false
+	}
+	return b.bits[i/8]&(1<<uint(i%8)) != 0
+}
+
+// Count returns the number of complete pieces.
+func (b *PieceBitmap) Count() (count int) {
+	for i := 0; i < b.n; i++ {
+		if b.Has(i) {
+			count++
+		}
+	}
+	return
+}
+
+func (c *Client) DownloadTrackers(id int) (trackers []DownloadTracker, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DownloadTrackersEP).As(params).Do(&trackers)
+	checkErr(err)
+	return
+}
+
+func (c *Client) AddDownloadTracker(id int, announce string) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	req := &DownloadTrackerReq{
+		Announce: announce,
+	}
+
+	err = c.Query(AddDownloadTrackerEP).As(params).WithBody(req).Do(nil)
+	checkErr(err)
+	return
+}
+
+// EnableDownloadTracker enables or disables the tracker without removing it.
+func (c *Client) EnableDownloadTracker(id int, announce string, enabled bool) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id":       strconv.Itoa(id),
+		"announce": url.PathEscape(announce),
+	}
+
+	req := &DownloadTrackerUpdateReq{
+		IsEnabled: enabled,
+	}
+
+	err = c.Query(UpdateDownloadTrackerEP).As(params).WithBody(req).Do(nil)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DeleteDownloadTracker(id int, announce string) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id":       strconv.Itoa(id),
+		"announce": url.PathEscape(announce),
+	}
+
+	err = c.Query(DeleteDownloadTrackerEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DownloadPeers(id int) (peers []DownloadPeer, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DownloadPeersEP).As(params).Do(&peers)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DownloadPieces(id int) (pieces *PieceBitmap, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	var states string
+	err = c.Query(DownloadPiecesEP).As(params).Do(&states)
+	checkErr(err)
+
+	pieces = NewPieceBitmap(states)
+	return
+}
+
+func (c *Client) DownloadBlacklist(id int) (entries []DownloadBlacklistEntry, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DownloadBlacklistEP).As(params).Do(&entries)
+	checkErr(err)
+	return
+}
+
+// AddBlacklist bans host from every torrent for expire.
+func (c *Client) AddBlacklist(host string, expire time.Duration) (entry *DownloadBlacklistEntry, err error) {
+	defer panicAttack(&err)
+
+	req := &DownloadBlacklistEntry{
+		Host:   host,
+		Expire: NewDuration(expire),
+	}
+
+	entry = new(DownloadBlacklistEntry)
+	err = c.Query(AddBlacklistEP).WithBody(req).Do(entry)
+	checkErr(err)
+	return
+}
+
+func (c *Client) EmptyDownloadBlacklist(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(EmptyDownloadBlacklistEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
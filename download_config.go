@@ -0,0 +1,281 @@
+package fbxapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ThrottlingMode limits the downloader rates, THROTTLING_MODE_SCHEDULE
+// following the ThrottlingSchedule of the configuration.
+type ThrottlingMode string
+
+const THROTTLING_MODE_NORMAL ThrottlingMode = "normal"
+const THROTTLING_MODE_SLOW ThrottlingMode = "slow"
+const THROTTLING_MODE_HIBERNATE ThrottlingMode = "hibernate"
+const THROTTLING_MODE_SCHEDULE ThrottlingMode = "schedule"
+
+func (m ThrottlingMode) String() string {
+	return string(m)
+}
+
+func (m ThrottlingMode) Known() bool {
+	switch m {
+	case THROTTLING_MODE_NORMAL, THROTTLING_MODE_SLOW, THROTTLING_MODE_HIBERNATE, THROTTLING_MODE_SCHEDULE:
+		return true
+	}
+	return false
+}
+
+// THROTTLING_SCHEDULE_SIZE is the number of hours of a week.
+const THROTTLING_SCHEDULE_SIZE = 7 * 24
+
+// ThrottlingSchedule holds the throttling mode of each hour of the week,
+// starting on monday at midnight. Entries are never THROTTLING_MODE_SCHEDULE.
+type ThrottlingSchedule [THROTTLING_SCHEDULE_SIZE]ThrottlingMode
+
+// scheduleIndex returns the entry of hour on day, ok is false when either is
+// out of range.
+func scheduleIndex(day time.Weekday, hour int) (index int, ok bool) {
+	if day < time.Sunday || day > time.Saturday || hour < 0 || hour > 23 {
+		return 0, false
+	}
+	return (int(day)+6)%7*24 + hour, true
+}
+
+// At returns the mode applied on day from hour to hour+1, the empty mode when
+// day or hour is out of range.
+func (s *ThrottlingSchedule) At(day time.Weekday, hour int) ThrottlingMode {
+	index, ok := scheduleIndex(day, hour)
+	if !ok {
+		return ""
+	}
+	return s[index]
+}
+
+// Set applies mode on day from hour to hour+1, out of range values are
+// ignored.
+func (s *ThrottlingSchedule) Set(day time.Weekday, hour int, mode ThrottlingMode) {
+	if index, ok := scheduleIndex(day, hour); ok {
+		s[index] = mode
+	}
+}
+
+// SetRange applies mode every day from the from hour to the to hour
+// excluded, to being at most 24 and wrapping around midnight when lower than
+// from. Out of range hours are ignored.
+func (s *ThrottlingSchedule) SetRange(from, to int, mode ThrottlingMode) {
+	if from < 0 || from > 23 || to < 0 || to > 24 {
+		return
+	}
+	hours := (to - from + 24) % 24
+	if hours == 0 && to != from {
+		hours = 24
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		for i := 0; i < hours; i++ {
+			s.Set(day, (from+i)%24, mode)
+		}
+	}
+}
+
+// ModeAt returns the mode applied at t, in the box time zone.
+func (s *ThrottlingSchedule) ModeAt(t time.Time) ThrottlingMode {
+	return s.At(t.Weekday(), t.Hour())
+}
+
+func (s *ThrottlingSchedule) UnmarshalJSON(data []byte) error {
+	var modes []ThrottlingMode
+	if err := json.Unmarshal(data, &modes); err != nil {
+		return err
+	}
+	if len(modes) != THROTTLING_SCHEDULE_SIZE {
+		return fmt.Errorf("throttling schedule has %d entries, expected %d", len(modes), THROTTLING_SCHEDULE_SIZE)
+	}
+	copy(s[:], modes)
+	return nil
+}
+
+type DownloadRate struct {
+	TXRate int `json:"tx_rate"`
+	RXRate int `json:"rx_rate"`
+}
+
+type DownloadThrottlingConfig struct {
+	Normal   DownloadRate       `json:"normal"`
+	Slow     DownloadRate       `json:"slow"`
+	Schedule ThrottlingSchedule `json:"schedule"`
+	Mode     ThrottlingMode     `json:"mode"`
+}
+
+type DownloadNewsConfig struct {
+	Server      string `json:"server"`
+	Port        int    `json:"port"`
+	SSL         bool   `json:"ssl"`
+	User        string `json:"user"`
+	Password    string `json:"password,omitempty"` // write only
+	NThreads    int    `json:"nthreads"`
+	AutoRepair  bool   `json:"auto_repair"`
+	LazyPar2    bool   `json:"lazy_par2"`
+	AutoExtract bool   `json:"auto_extract"`
+	EraseTmp    bool   `json:"erase_tmp"`
+}
+
+type DownloadBTConfig struct {
+	MaxPeers        int      `json:"max_peers"`
+	StopRatio       int      `json:"stop_ratio"`
+	CryptoSupport   string   `json:"crypto_support"`
+	EnableDHT       bool     `json:"enable_dht"`
+	EnablePEX       bool     `json:"enable_pex"`
+	AnnounceTimeout Duration `json:"announce_timeout"`
+	MainPort        int      `json:"main_port"`
+	DHTPort         int      `json:"dht_port"`
+}
+
+type DownloadFeedConfig struct {
+	FetchInterval Duration `json:"fetch_interval"`
+	MaxItems      int      `json:"max_items"`
+}
+
+type DownloadBlocklistConfig struct {
+	Sources []string `json:"sources"`
+}
+
+type DownloadConfig struct {
+	MaxDownloadingTasks int                      `json:"max_downloading_tasks"`
+	DownloadDir         FilePath                 `json:"download_dir"`
+	WatchDir            FilePath                 `json:"watch_dir"`
+	UseWatchDir         bool                     `json:"use_watch_dir"`
+	Throttling          DownloadThrottlingConfig `json:"throttling"`
+	News                DownloadNewsConfig       `json:"news"`
+	BT                  DownloadBTConfig         `json:"bt"`
+	Feed                DownloadFeedConfig       `json:"feed"`
+	Blocklist           DownloadBlocklistConfig  `json:"blocklist"`
+}
+
+type DownloadNZBConfigStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type DownloadDHTStats struct {
+	Enabled   bool `json:"enabled"`
+	NodeCount int  `json:"node_count"`
+}
+
+type DownloadStats struct {
+	NbTasks               int                     `json:"nb_tasks"`
+	NbTasksActive         int                     `json:"nb_tasks_active"`
+	NbTasksStopped        int                     `json:"nb_tasks_stopped"`
+	NbTasksQueued         int                     `json:"nb_tasks_queued"`
+	NbTasksRepairing      int                     `json:"nb_tasks_repairing"`
+	NbTasksExtracting     int                     `json:"nb_tasks_extracting"`
+	NbTasksError          int                     `json:"nb_tasks_error"`
+	NbTasksChecking       int                     `json:"nb_tasks_checking"`
+	NbTasksDone           int                     `json:"nb_tasks_done"`
+	NbTasksDownloading    int                     `json:"nb_tasks_downloading"`
+	NbRSS                 int                     `json:"nb_rss"`
+	NbRSSItemsUnread      int                     `json:"nb_rss_items_unread"`
+	RXRate                int                     `json:"rx_rate"`
+	TXRate                int                     `json:"tx_rate"`
+	ThrottlingMode        ThrottlingMode          `json:"throttling_mode"`
+	ThrottlingIsScheduled bool                    `json:"throttling_is_scheduled"`
+	ThrottlingRate        DownloadRate            `json:"throttling_rate"`
+	NZBConfigStatus       DownloadNZBConfigStatus `json:"nzb_config_status"`
+	DHTStats              DownloadDHTStats        `json:"dht_stats"`
+}
+
+type ThrottlingReq struct {
+	Throttling ThrottlingMode `json:"throttling"`
+}
+
+// DownloadStatsEP endpoint definition
+// Output: DownloadStats
+var DownloadStatsEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/stats/",
+}
+
+// DownloadConfigEP endpoint definition
+// Output: DownloadConfig
+var DownloadConfigEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/config/",
+}
+
+// UpdateDownloadConfigEP endpoint definition
+// Output: DownloadConfig
+var UpdateDownloadConfigEP = &Endpoint{
+	Verb:         HTTP_METHOD_PUT,
+	Url:          "downloads/config/",
+	BodyRequired: true,
+}
+
+// ThrottlingEP endpoint definition
+// Output: nil
+var ThrottlingEP = &Endpoint{
+	Verb:         HTTP_METHOD_PUT,
+	Url:          "downloads/throttling",
+	BodyRequired: true,
+}
+
+func (c *Client) DownloadStats() (stats *DownloadStats, err error) {
+	defer panicAttack(&err)
+
+	stats = new(DownloadStats)
+	err = c.Query(DownloadStatsEP).Do(stats)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DownloadConfig() (config *DownloadConfig, err error) {
+	defer panicAttack(&err)
+
+	config = new(DownloadConfig)
+	err = c.Query(DownloadConfigEP).Do(config)
+	checkErr(err)
+	return
+}
+
+// UpdateDownloadConfig fetches the configuration, lets change modify it and
+// only sends the fields it changed. It returns the configuration as applied
+// by the box.
+func (c *Client) UpdateDownloadConfig(change func(config *DownloadConfig)) (config *DownloadConfig, err error) {
+	defer panicAttack(&err)
+
+	config, err = c.DownloadConfig()
+	checkErr(err)
+
+	current, err := toJSONMap(config)
+	checkErr(err)
+	change(config)
+	changed, err := toJSONMap(config)
+	checkErr(err)
+
+	diff := jsonDiff(current, changed)
+	if len(diff) == 0 {
+		return
+	}
+
+	config = new(DownloadConfig)
+	err = c.Query(UpdateDownloadConfigEP).WithBody(diff).Do(config)
+	checkErr(err)
+	return
+}
+
+// SetThrottling switches the throttling mode until the next change, use
+// THROTTLING_MODE_SCHEDULE to go back to the configured schedule.
+func (c *Client) SetThrottling(mode ThrottlingMode) (err error) {
+	defer panicAttack(&err)
+
+	err = checkEnum(mode, "throttling mode")
+	checkErr(err)
+
+	req := &ThrottlingReq{
+		Throttling: mode,
+	}
+
+	err = c.Query(ThrottlingEP).WithBody(req).Do(nil)
+	checkErr(err)
+	return
+}
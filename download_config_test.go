@@ -0,0 +1,98 @@
+package fbxapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDownloadStats(t *testing.T) {
+	EndpointTester(t, DownloadStatsEP, &DownloadStats{}, nil, nil)
+}
+
+func TestDownloadConfig(t *testing.T) {
+	EndpointTester(t, DownloadConfigEP, &DownloadConfig{}, nil, nil)
+}
+
+func TestUpdateDownloadConfig(t *testing.T) {
+	config, err := testClient.DownloadConfig()
+	failOnError(t, err)
+	maxTasks := config.MaxDownloadingTasks
+
+	updated, err := testClient.UpdateDownloadConfig(func(config *DownloadConfig) {
+		config.MaxDownloadingTasks = maxTasks + 1
+	})
+	failOnError(t, err)
+	if updated.MaxDownloadingTasks != maxTasks+1 {
+		t.Fatalf("expected %d max tasks, got %d", maxTasks+1, updated.MaxDownloadingTasks)
+	}
+
+	_, err = testClient.UpdateDownloadConfig(func(config *DownloadConfig) {
+		config.MaxDownloadingTasks = maxTasks
+	})
+	failOnError(t, err)
+}
+
+func TestThrottling(t *testing.T) {
+	stats, err := testClient.DownloadStats()
+	failOnError(t, err)
+
+	mode := stats.ThrottlingMode
+	if stats.ThrottlingIsScheduled {
+		mode = THROTTLING_MODE_SCHEDULE
+	}
+	defer testClient.SetThrottling(mode)
+
+	err = testClient.SetThrottling(THROTTLING_MODE_SLOW)
+	failOnError(t, err)
+
+	stats, err = testClient.DownloadStats()
+	failOnError(t, err)
+	if stats.ThrottlingMode != THROTTLING_MODE_SLOW {
+		t.Fatalf("unexpected throttling mode %s", stats.ThrottlingMode)
+	}
+}
+
+func TestThrottlingSchedule(t *testing.T) {
+	var schedule ThrottlingSchedule
+	schedule.SetRange(0, 24, THROTTLING_MODE_NORMAL)
+	schedule.SetRange(22, 7, THROTTLING_MODE_HIBERNATE)
+
+	if schedule[0] != THROTTLING_MODE_HIBERNATE || schedule[7] != THROTTLING_MODE_NORMAL {
+		t.Fatal("schedule does not start on monday midnight")
+	}
+	if schedule.At(time.Sunday, 23) != THROTTLING_MODE_HIBERNATE || schedule[THROTTLING_SCHEDULE_SIZE-1] != THROTTLING_MODE_HIBERNATE {
+		t.Fatal("sunday is not the last day of the schedule")
+	}
+
+	tuesdayNoon := time.Date(2018, 1, 2, 12, 0, 0, 0, time.Local)
+	if schedule.ModeAt(tuesdayNoon) != THROTTLING_MODE_NORMAL {
+		t.Fatalf("unexpected mode %s", schedule.ModeAt(tuesdayNoon))
+	}
+
+	if schedule.At(time.Monday, 24) != "" || schedule.At(time.Weekday(7), 0) != "" || schedule.At(time.Monday, -1) != "" {
+		t.Fatal("out of range hour returned a mode")
+	}
+	before := schedule
+	schedule.Set(time.Weekday(-1), 3, THROTTLING_MODE_SLOW)
+	schedule.SetRange(-2, 30, THROTTLING_MODE_SLOW)
+	if schedule != before {
+		t.Fatal("out of range hours changed the schedule")
+	}
+}
+
+func TestJSONDiff(t *testing.T) {
+	current, err := toJSONMap(&DownloadConfig{UseWatchDir: true})
+	failOnError(t, err)
+
+	config := &DownloadConfig{UseWatchDir: true}
+	config.BT.MaxPeers = 50
+	changed, err := toJSONMap(config)
+	failOnError(t, err)
+
+	diff := jsonDiff(current, changed)
+	bt, ok := diff["bt"].(map[string]interface{})
+	if len(diff) != 1 || !ok || len(bt) != 1 || bt["max_peers"].(json.Number) != "50" {
+		t.Fatalf("unexpected diff %v", diff)
+	}
+}
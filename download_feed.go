@@ -0,0 +1,291 @@
+package fbxapi
+
+import (
+	"strconv"
+)
+
+// DownloadFeedStatus is the fetch state of a RSS feed.
+type DownloadFeedStatus string
+
+const DOWNLOAD_FEED_STATUS_READY DownloadFeedStatus = "ready"
+const DOWNLOAD_FEED_STATUS_FETCHING DownloadFeedStatus = "fetching"
+const DOWNLOAD_FEED_STATUS_ERROR DownloadFeedStatus = "error"
+
+func (s DownloadFeedStatus) String() string {
+	return string(s)
+}
+
+func (s DownloadFeedStatus) Known() bool {
+	switch s {
+	case DOWNLOAD_FEED_STATUS_READY, DOWNLOAD_FEED_STATUS_FETCHING, DOWNLOAD_FEED_STATUS_ERROR:
+		return true
+	}
+	return false
+}
+
+type DownloadFeed struct {
+	ID           int                `json:"id"`
+	Status       DownloadFeedStatus `json:"status"`
+	URL          string             `json:"url"`
+	Title        string             `json:"title"`
+	Desc         string             `json:"desc"`
+	ImageURL     string             `json:"image_url"`
+	FetchTS      Timestamp          `json:"fetch_ts"`
+	PubDate      Timestamp          `json:"pub_date"`
+	AutoDownload bool               `json:"auto_download"`
+	NbItems      int                `json:"nb_items"`
+	NbUnread     int                `json:"nb_unread"`
+}
+
+type DownloadFeedItem struct {
+	ID              string    `json:"id"`
+	FeedID          int       `json:"feed_id"`
+	Title           string    `json:"title"`
+	Desc            string    `json:"desc"`
+	Author          string    `json:"author"`
+	Link            string    `json:"link"`
+	IsRead          bool      `json:"is_read"`
+	IsDownloaded    bool      `json:"is_downloaded"`
+	FetchTS         Timestamp `json:"fetch_ts"`
+	PubDate         Timestamp `json:"pub_date"`
+	EnclosureURL    string    `json:"enclosure_url"`
+	EnclosureType   string    `json:"enclosure_type"`
+	EnclosureLength int       `json:"enclosure_length"`
+}
+
+type DownloadFeedReq struct {
+	URL string `json:"url"`
+}
+
+type DownloadFeedUpdateReq struct {
+	AutoDownload bool `json:"auto_download"`
+}
+
+type DownloadFeedItemUpdateReq struct {
+	IsRead bool `json:"is_read"`
+}
+
+// DownloadFeedsEP endpoint definition
+// Output: []DownloadFeed
+var DownloadFeedsEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/feeds/",
+}
+
+// DownloadFeedEP endpoint definition
+// Output: DownloadFeed
+var DownloadFeedEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/feeds/{{.id}}",
+}
+
+// AddDownloadFeedEP endpoint definition
+// Output: DownloadFeed
+var AddDownloadFeedEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "downloads/feeds/",
+	BodyRequired: true,
+}
+
+// UpdateDownloadFeedEP endpoint definition
+// Output: DownloadFeed
+var UpdateDownloadFeedEP = &Endpoint{
+	Verb:         HTTP_METHOD_PUT,
+	Url:          "downloads/feeds/{{.id}}",
+	BodyRequired: true,
+}
+
+// DeleteDownloadFeedEP endpoint definition
+// Output: nil
+var DeleteDownloadFeedEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "downloads/feeds/{{.id}}",
+}
+
+// RefreshDownloadFeedEP endpoint definition
+// Output: nil
+var RefreshDownloadFeedEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/feeds/{{.id}}/fetch",
+}
+
+// RefreshDownloadFeedsEP endpoint definition
+// Output: nil
+var RefreshDownloadFeedsEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/feeds/fetch",
+}
+
+// DownloadFeedItemsEP endpoint definition
+// Output: []DownloadFeedItem
+var DownloadFeedItemsEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/feeds/{{.id}}/items",
+}
+
+// UpdateDownloadFeedItemEP endpoint definition
+// Output: DownloadFeedItem
+var UpdateDownloadFeedItemEP = &Endpoint{
+	Verb:         HTTP_METHOD_PUT,
+	Url:          "downloads/feeds/{{.id}}/items/{{.item_id}}",
+	BodyRequired: true,
+}
+
+// DownloadFeedItemDownloadEP endpoint definition
+// Output: nil
+var DownloadFeedItemDownloadEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/feeds/{{.id}}/items/{{.item_id}}/download",
+}
+
+// MarkAllDownloadFeedItemsReadEP endpoint definition
+// Output: nil
+var MarkAllDownloadFeedItemsReadEP = &Endpoint{
+	Verb: HTTP_METHOD_POST,
+	Url:  "downloads/feeds/{{.id}}/items/mark_all_as_read",
+}
+
+func (c *Client) DownloadFeeds() (feeds []DownloadFeed, err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(DownloadFeedsEP).Do(&feeds)
+	checkErr(err)
+	return
+}
+
+func (c *Client) GetDownloadFeed(id int) (feed *DownloadFeed, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	feed = new(DownloadFeed)
+	err = c.Query(DownloadFeedEP).As(params).Do(feed)
+	checkErr(err)
+	return
+}
+
+// AddDownloadFeed subscribes to the RSS feed at url, it is fetched in the
+// background.
+func (c *Client) AddDownloadFeed(url string) (feed *DownloadFeed, err error) {
+	defer panicAttack(&err)
+
+	req := &DownloadFeedReq{
+		URL: url,
+	}
+
+	feed = new(DownloadFeed)
+	err = c.Query(AddDownloadFeedEP).WithBody(req).Do(feed)
+	checkErr(err)
+	return
+}
+
+// SetDownloadFeedAutoDownload makes the box download, or not, every new item
+// of the feed.
+func (c *Client) SetDownloadFeedAutoDownload(id int, autoDownload bool) (feed *DownloadFeed, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	req := &DownloadFeedUpdateReq{
+		AutoDownload: autoDownload,
+	}
+
+	feed = new(DownloadFeed)
+	err = c.Query(UpdateDownloadFeedEP).As(params).WithBody(req).Do(feed)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DeleteDownloadFeed(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DeleteDownloadFeedEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// RefreshDownloadFeed fetches the feed again, in the background.
+func (c *Client) RefreshDownloadFeed(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(RefreshDownloadFeedEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// RefreshDownloadFeeds fetches every feed again, in the background.
+func (c *Client) RefreshDownloadFeeds() (err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(RefreshDownloadFeedsEP).Do(nil)
+	checkErr(err)
+	return
+}
+
+func (c *Client) DownloadFeedItems(id int) (items []DownloadFeedItem, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DownloadFeedItemsEP).As(params).Do(&items)
+	checkErr(err)
+	return
+}
+
+func (c *Client) MarkDownloadFeedItemRead(id int, itemID string, read bool) (item *DownloadFeedItem, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id":      strconv.Itoa(id),
+		"item_id": itemID,
+	}
+
+	req := &DownloadFeedItemUpdateReq{
+		IsRead: read,
+	}
+
+	item = new(DownloadFeedItem)
+	err = c.Query(UpdateDownloadFeedItemEP).As(params).WithBody(req).Do(item)
+	checkErr(err)
+	return
+}
+
+// DownloadFromFeedItem queues the enclosure of the item in the downloader.
+func (c *Client) DownloadFromFeedItem(id int, itemID string) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id":      strconv.Itoa(id),
+		"item_id": itemID,
+	}
+
+	err = c.Query(DownloadFeedItemDownloadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+func (c *Client) MarkAllDownloadFeedItemsRead(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(MarkAllDownloadFeedItemsReadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
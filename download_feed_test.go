@@ -0,0 +1,43 @@
+package fbxapi
+
+import (
+	"testing"
+)
+
+func TestDownloadFeeds(t *testing.T) {
+	EndpointTester(t, DownloadFeedsEP, &[]DownloadFeed{}, nil, nil)
+}
+
+func TestDownloadFeed(t *testing.T) {
+	feed, err := testClient.AddDownloadFeed("https://www.debian.org/News/news")
+	failOnError(t, err)
+	defer testClient.DeleteDownloadFeed(feed.ID)
+
+	feed, err = testClient.SetDownloadFeedAutoDownload(feed.ID, false)
+	failOnError(t, err)
+	if feed.AutoDownload {
+		t.Fatal("auto download still enabled")
+	}
+
+	err = testClient.RefreshDownloadFeed(feed.ID)
+	failOnError(t, err)
+
+	items, err := testClient.DownloadFeedItems(feed.ID)
+	failOnError(t, err)
+	if len(items) > 0 {
+		item, err := testClient.MarkDownloadFeedItemRead(feed.ID, items[0].ID, true)
+		failOnError(t, err)
+		if !item.IsRead {
+			t.Fatal("item not marked as read")
+		}
+	}
+
+	err = testClient.MarkAllDownloadFeedItemsRead(feed.ID)
+	failOnError(t, err)
+
+	feed, err = testClient.GetDownloadFeed(feed.ID)
+	failOnError(t, err)
+	if feed.NbUnread != 0 {
+		t.Fatalf("%d unread items left", feed.NbUnread)
+	}
+}
@@ -0,0 +1,176 @@
+package fbxapi
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DownloadFilePriority tells whether and how eagerly a file of a download is
+// fetched.
+type DownloadFilePriority string
+
+const DOWNLOAD_FILE_PRIORITY_SKIP DownloadFilePriority = "no_dl"
+const DOWNLOAD_FILE_PRIORITY_LOW DownloadFilePriority = "low"
+const DOWNLOAD_FILE_PRIORITY_NORMAL DownloadFilePriority = "normal"
+const DOWNLOAD_FILE_PRIORITY_HIGH DownloadFilePriority = "high"
+
+func (p DownloadFilePriority) String() string {
+	return string(p)
+}
+
+func (p DownloadFilePriority) Known() bool {
+	switch p {
+	case DOWNLOAD_FILE_PRIORITY_SKIP, DOWNLOAD_FILE_PRIORITY_LOW, DOWNLOAD_FILE_PRIORITY_NORMAL, DOWNLOAD_FILE_PRIORITY_HIGH:
+		return true
+	}
+	return false
+}
+
+// DownloadFileStatus is the transfer state of a single file of a download.
+type DownloadFileStatus string
+
+const DOWNLOAD_FILE_STATUS_QUEUED DownloadFileStatus = "queued"
+const DOWNLOAD_FILE_STATUS_DOWNLOADING DownloadFileStatus = "downloading"
+const DOWNLOAD_FILE_STATUS_DONE DownloadFileStatus = "done"
+const DOWNLOAD_FILE_STATUS_ERROR DownloadFileStatus = "error"
+
+func (s DownloadFileStatus) String() string {
+	return string(s)
+}
+
+func (s DownloadFileStatus) Known() bool {
+	switch s {
+	case DOWNLOAD_FILE_STATUS_QUEUED, DOWNLOAD_FILE_STATUS_DOWNLOADING, DOWNLOAD_FILE_STATUS_DONE, DOWNLOAD_FILE_STATUS_ERROR:
+		return true
+	}
+	return false
+}
+
+func (s DownloadFileStatus) IsTerminal() bool {
+	return s == DOWNLOAD_FILE_STATUS_DONE || s == DOWNLOAD_FILE_STATUS_ERROR
+}
+
+type DownloadFile struct {
+	ID     string   `json:"id"`
+	TaskID int      `json:"task_id"`
+	Path   FilePath `json:"path"`
+	// FilePath is the path of the file inside the download, it is sent
+	// unencoded.
+	FilePath string               `json:"filepath"`
+	Name     string               `json:"name"`
+	MimeType string               `json:"mimetype"`
+	Size     int                  `json:"size"`
+	RX       int                  `json:"rx"`
+	Status   DownloadFileStatus   `json:"status"`
+	Error    string               `json:"error"`
+	Priority DownloadFilePriority `json:"priority"`
+	LongName string               `json:"long_name"`
+}
+
+// Progress returns the received fraction of the file, between 0 and 1.
+func (f *DownloadFile) Progress() float64 {
+	if f.Size == 0 {
+		return 0
+	}
+	return float64(f.RX) / float64(f.Size)
+}
+
+type DownloadFileUpdateReq struct {
+	Priority DownloadFilePriority `json:"priority"`
+}
+
+// DownloadFilesEP endpoint definition
+// Output: []DownloadFile
+var DownloadFilesEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/files",
+}
+
+// UpdateDownloadFileEP endpoint definition
+// Output: DownloadFile
+var UpdateDownloadFileEP = &Endpoint{
+	Verb: HTTP_METHOD_PUT,
+	Url:  "downloads/{{.id}}/files/{{.file_id}}",
+}
+
+// DownloadFileMatcher selects files of a download.
+type DownloadFileMatcher func(file *DownloadFile) bool
+
+// MatchDownloadFileGlob selects files whose name matches pattern, or whose
+// path inside the download does when pattern holds a slash.
+func MatchDownloadFileGlob(pattern string) DownloadFileMatcher {
+	return func(file *DownloadFile) bool {
+		name := file.Name
+		if strings.Contains(pattern, "/") {
+			name = strings.TrimPrefix(file.FilePath, "/")
+		}
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+}
+
+// MatchDownloadFileMimeType selects files whose mime type matches pattern,
+// such as "video/*".
+func MatchDownloadFileMimeType(pattern string) DownloadFileMatcher {
+	return func(file *DownloadFile) bool {
+		matched, _ := path.Match(pattern, file.MimeType)
+		return matched
+	}
+}
+
+func (c *Client) DownloadFiles(id int) (files []DownloadFile, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DownloadFilesEP).As(params).Do(&files)
+	checkErr(err)
+	return
+}
+
+func (c *Client) UpdateDownloadFile(id int, fileID string, priority DownloadFilePriority) (file *DownloadFile, err error) {
+	defer panicAttack(&err)
+
+	err = checkEnum(priority, "download file priority")
+	checkErr(err)
+
+	params := map[string]string{
+		"id":      strconv.Itoa(id),
+		"file_id": fileID,
+	}
+
+	req := &DownloadFileUpdateReq{
+		Priority: priority,
+	}
+
+	file = new(DownloadFile)
+	err = c.Query(UpdateDownloadFileEP).As(params).WithBody(req).Do(file)
+	checkErr(err)
+	return
+}
+
+// SetDownloadFilesPriority sets priority on the files of the download
+// selected by match and returns them updated. Use
+// DOWNLOAD_FILE_PRIORITY_SKIP to leave files out.
+func (c *Client) SetDownloadFilesPriority(id int, priority DownloadFilePriority, match DownloadFileMatcher) (updated []DownloadFile, err error) {
+	defer panicAttack(&err)
+
+	err = checkEnum(priority, "download file priority")
+	checkErr(err)
+
+	files, err := c.DownloadFiles(id)
+	checkErr(err)
+
+	for i := range files {
+		if !match(&files[i]) || files[i].Priority == priority {
+			continue
+		}
+		file, err := c.UpdateDownloadFile(id, files[i].ID, priority)
+		checkErr(err)
+		updated = append(updated, *file)
+	}
+	return
+}
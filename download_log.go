@@ -0,0 +1,63 @@
+package fbxapi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DOWNLOAD_LOG_TIME_LAYOUT is the layout of the date starting log lines.
+const DOWNLOAD_LOG_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var downloadLogLineRe = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\]?\s+(?:\[?([a-z]+)\]?:?\s+)?(.*)$`)
+
+// DownloadLogLine is a line of a download log. Time and Level are only set
+// when the line starts with them, Msg holds the rest of the line.
+type DownloadLogLine struct {
+	Time  time.Time
+	Level string
+	Msg   string
+	Raw   string
+}
+
+// DownloadLogEP endpoint definition
+// Output: string
+var DownloadLogEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "downloads/{{.id}}/log",
+}
+
+func parseDownloadLog(log string) (lines []DownloadLogLine) {
+	for _, raw := range strings.Split(log, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		if raw == "" {
+			continue
+		}
+
+		line := DownloadLogLine{Msg: raw, Raw: raw}
+		if m := downloadLogLineRe.FindStringSubmatch(raw); m != nil {
+			if t, err := time.ParseInLocation(DOWNLOAD_LOG_TIME_LAYOUT, m[1], time.Local); err == nil {
+				line.Time, line.Level, line.Msg = t, m[2], m[3]
+			}
+		}
+		lines = append(lines, line)
+	}
+	return
+}
+
+// DownloadLog returns the log of the download, one entry per line.
+func (c *Client) DownloadLog(id int) (lines []DownloadLogLine, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	var log string
+	err = c.Query(DownloadLogEP).As(params).Do(&log)
+	checkErr(err)
+
+	lines = parseDownloadLog(log)
+	return
+}
@@ -1,16 +1,187 @@
 package fbxapi
 
 import (
+	"context"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDownload(t *testing.T) {
-	var data DownloadTask
 	req := &DownloadReq{
 		DownloadUrl: "http://ftp.free.fr/mirrors/cdimage.debian.org/debian-cd/current/i386/iso-cd/debian-9.3.0-i386-netinst.iso",
-		DownloadDir: EncodePath("/Disque dur/Téléchargements/"),
+		DownloadDir: FilePath("/Disque dur/Téléchargements/"),
 	}
 
-	err := testClient.Query(AddDownloadEP).WithFormBody(req).Do(&data)
+	id, err := testClient.AddDownload(req)
 	failOnError(t, err)
+	defer testClient.EraseDownload(id)
+
+	EndpointTester(t, DownloadsEP, &[]Download{}, nil, nil)
+
+	download, err := testClient.GetDownload(id)
+	failOnError(t, err)
+	if download.ID != id {
+		t.Fatalf("expected download %d, got %d", id, download.ID)
+	}
+
+	download, err = testClient.UpdateDownload(id, &DownloadUpdateReq{
+		Status:     DOWNLOAD_STATUS_STOPPED,
+		IOPriority: DOWNLOAD_IO_PRIORITY_LOW,
+	})
+	failOnError(t, err)
+	if download.IOPriority != DOWNLOAD_IO_PRIORITY_LOW {
+		t.Fatalf("unexpected io priority %s", download.IOPriority)
+	}
+
+	_, err = testClient.UpdateDownload(id, &DownloadUpdateReq{Status: DOWNLOAD_STATUS_DONE})
+	if err == nil {
+		t.Fatal("status done accepted")
+	}
+
+	downloads, err := testClient.ListDownloads()
+	failOnError(t, err)
+	found := false
+	for _, d := range downloads {
+		found = found || d.ID == id
+	}
+	if !found {
+		t.Fatalf("download %d not listed", id)
+	}
+}
+
+// tinyTorrent is a trackerless torrent of a single one byte file.
+const tinyTorrent = "d4:infod6:lengthi1e4:name8:fbxapi.a12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaaee"
+
+func TestAddDownloadFile(t *testing.T) {
+	opts := &AddDownloadFileOptions{
+		DownloadDir: "/Disque dur/Téléchargements/",
+		Paused:      true,
+	}
+
+	id, err := testClient.AddDownloadFile(context.Background(), strings.NewReader(tinyTorrent), "fbxapi.torrent", opts)
+	failOnError(t, err)
+	defer testClient.EraseDownload(id)
+
+	download, err := testClient.GetDownload(id)
+	failOnError(t, err)
+	if download.Type != "bt" || download.Status != DOWNLOAD_STATUS_STOPPED {
+		t.Fatalf("unexpected download %s %s", download.Type, download.Status)
+	}
+}
+
+func TestDownloadFiles(t *testing.T) {
+	id, err := testClient.AddDownloadFile(context.Background(), strings.NewReader(tinyTorrent), "fbxapi.torrent", &AddDownloadFileOptions{Paused: true})
+	failOnError(t, err)
+	defer testClient.EraseDownload(id)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+	EndpointTester(t, DownloadFilesEP, &[]DownloadFile{}, params, nil)
+
+	updated, err := testClient.SetDownloadFilesPriority(id, DOWNLOAD_FILE_PRIORITY_SKIP, MatchDownloadFileGlob("*.a"))
+	failOnError(t, err)
+	if len(updated) != 1 || updated[0].Priority != DOWNLOAD_FILE_PRIORITY_SKIP {
+		t.Fatalf("unexpected files %#v", updated)
+	}
+}
+
+func TestMatchDownloadFile(t *testing.T) {
+	file := &DownloadFile{
+		Name:     "movie.mkv",
+		Path:     FilePath("/Disque dur/Téléchargements/Show/Season 1/movie.mkv"),
+		FilePath: "Season 1/movie.mkv",
+		MimeType: "video/x-matroska",
+	}
+
+	if !MatchDownloadFileGlob("*.mkv")(file) || MatchDownloadFileGlob("*.nfo")(file) {
+		t.Fatal("unexpected name match")
+	}
+	if !MatchDownloadFileGlob("Season 1/*")(file) {
+		t.Fatal("unexpected path match")
+	}
+	if !MatchDownloadFileMimeType("video/*")(file) || MatchDownloadFileMimeType("audio/*")(file) {
+		t.Fatal("unexpected mime type match")
+	}
+}
+
+func TestDownloadBT(t *testing.T) {
+	id, err := testClient.AddDownloadFile(context.Background(), strings.NewReader(tinyTorrent), "fbxapi.torrent", &AddDownloadFileOptions{Paused: true})
+	failOnError(t, err)
+	defer testClient.EraseDownload(id)
+
+	const announce = "udp://tracker.example.org:1337/announce"
+	err = testClient.AddDownloadTracker(id, announce)
+	failOnError(t, err)
+
+	trackers, err := testClient.DownloadTrackers(id)
+	failOnError(t, err)
+	if len(trackers) == 0 || trackers[len(trackers)-1].Announce != announce {
+		t.Fatalf("tracker not added: %#v", trackers)
+	}
+
+	err = testClient.EnableDownloadTracker(id, announce, false)
+	failOnError(t, err)
+	err = testClient.DeleteDownloadTracker(id, announce)
+	failOnError(t, err)
+
+	_, err = testClient.DownloadPeers(id)
+	failOnError(t, err)
+
+	pieces, err := testClient.DownloadPieces(id)
+	failOnError(t, err)
+	if pieces.Len() != 1 {
+		t.Fatalf("expected 1 piece, got %d", pieces.Len())
+	}
+
+	_, err = testClient.DownloadBlacklist(id)
+	failOnError(t, err)
+	err = testClient.EmptyDownloadBlacklist(id)
+	failOnError(t, err)
+}
+
+func TestPieceBitmap(t *testing.T) {
+	pieces := NewPieceBitmap("XX./.....X")
+	if pieces.Len() != 10 || pieces.Count() != 3 {
+		t.Fatalf("unexpected bitmap %d %d", pieces.Len(), pieces.Count())
+	}
+	if !pieces.Has(9) || pieces.Has(3) || pieces.Has(10) {
+		t.Fatal("unexpected piece state")
+	}
+}
+
+func TestWaitDownload(t *testing.T) {
+	id, err := testClient.AddDownloadFile(context.Background(), strings.NewReader(tinyTorrent), "fbxapi.torrent", &AddDownloadFileOptions{Paused: true})
+	failOnError(t, err)
+	defer testClient.EraseDownload(id)
+
+	_, err = testClient.DownloadLog(id)
+	failOnError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	progress := make(chan DownloadProgress, 10)
+	_, err = testClient.WaitDownload(ctx, id, progress)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected a timeout waiting a stopped download, got %v", err)
+	}
+	if state := <-progress; state.ID != id || state.Status != DOWNLOAD_STATUS_STOPPED {
+		t.Fatalf("unexpected progress %#v", state)
+	}
+}
+
+func TestParseDownloadLog(t *testing.T) {
+	lines := parseDownloadLog("2018-01-02 10:20:30 [err] tracker unreachable\nplain line\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Level != "err" || lines[0].Msg != "tracker unreachable" || lines[0].Time.Hour() != 10 {
+		t.Fatalf("unexpected line %#v", lines[0])
+	}
+	if !lines[1].Time.IsZero() || lines[1].Msg != "plain line" {
+		t.Fatalf("unexpected line %#v", lines[1])
+	}
 }
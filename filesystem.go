@@ -3,72 +3,219 @@ package fbxapi
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
-
-	"golang.org/x/net/websocket"
 )
 
+// FSTaskType is the kind of operation run by a file system task.
+type FSTaskType string
+
+const FS_TASK_TYPE_COPY FSTaskType = "cp"
+const FS_TASK_TYPE_MOVE FSTaskType = "mv"
+const FS_TASK_TYPE_REMOVE FSTaskType = "rm"
+const FS_TASK_TYPE_ARCHIVE FSTaskType = "archive"
+const FS_TASK_TYPE_EXTRACT FSTaskType = "extract"
+const FS_TASK_TYPE_REPAIR FSTaskType = "repair"
+const FS_TASK_TYPE_HASH FSTaskType = "hash"
+
+func (t FSTaskType) String() string {
+	return string(t)
+}
+
+func (t FSTaskType) Known() bool {
+	switch t {
+	case FS_TASK_TYPE_COPY, FS_TASK_TYPE_MOVE, FS_TASK_TYPE_REMOVE, FS_TASK_TYPE_ARCHIVE,
+		FS_TASK_TYPE_EXTRACT, FS_TASK_TYPE_REPAIR, FS_TASK_TYPE_HASH:
+		return true
+	}
+	return false
+}
+
+// FSTaskState is the progress state of a file system task.
+type FSTaskState string
+
+const FS_TASK_STATE_QUEUED FSTaskState = "queued"
+const FS_TASK_STATE_RUNNING FSTaskState = "running"
+const FS_TASK_STATE_PAUSED FSTaskState = "paused"
+const FS_TASK_STATE_DONE FSTaskState = "done"
+const FS_TASK_STATE_FAILED FSTaskState = "failed"
+
+func (s FSTaskState) String() string {
+	return string(s)
+}
+
+func (s FSTaskState) Known() bool {
+	switch s {
+	case FS_TASK_STATE_QUEUED, FS_TASK_STATE_RUNNING, FS_TASK_STATE_PAUSED,
+		FS_TASK_STATE_DONE, FS_TASK_STATE_FAILED:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether a task in this state will not make any more
+// progress.
+func (s FSTaskState) IsTerminal() bool {
+	return s == FS_TASK_STATE_DONE || s == FS_TASK_STATE_FAILED
+}
+
+const FS_TASK_ERROR_NONE = "none"
+const FS_TASK_ERROR_REPAIR_FAILED = "repair_failed"
+
+// ErrRepairFailed is returned by FSTask.Err when par2 could not repair the
+// files.
+var ErrRepairFailed = errors.New("repair failed")
+
+// Conflict modes of the copy and move tasks.
+const FS_CONFLICT_OVERWRITE = "overwrite"
+const FS_CONFLICT_BOTH = "both"
+const FS_CONFLICT_SKIP = "skip"
+const FS_CONFLICT_RECENT = "recent"
+
+const HASH_TYPE_MD5 = "md5"
+const HASH_TYPE_SHA1 = "sha1"
+const HASH_TYPE_SHA256 = "sha256"
+const HASH_TYPE_SHA512 = "sha512"
+
+// FS_TASK_POLL_INTERVAL is the delay between two task states in WaitTask.
+const FS_TASK_POLL_INTERVAL = time.Second
+
 type FSTask struct {
-	ID             int    `json:"id"`
-	Type           string `json:"type"`
-	State          string `json:"state"`
-	Error          string `json:"error"`
-	CreatedTS      int    `json:"created_ts"`
-	StartedTS      int    `json:"started_ts"`
-	DoneTS         int    `json:"done_ts"`
-	Duration       int    `json:"duration"`
-	Progress       int    `json:"progress"`
-	ETA            int    `json:"eta"`
-	From           string `json:"from"`
-	To             string `json:"to"`
-	NFiles         int    `json:"nfiles"`
-	NFilesDone     int    `json:"nfiles_done"`
-	TotalBytes     int    `json:"total_bytes"`
-	TotalBytesDone int    `json:"total_bytes_done"`
-	CurrBytes      int    `json:"curr_bytes"`
-	Rate           int    `json:"rate"`
+	ID             int         `json:"id"`
+	Type           FSTaskType  `json:"type"`
+	State          FSTaskState `json:"state"`
+	Error          string      `json:"error"`
+	CreatedTS      Timestamp   `json:"created_ts"`
+	StartedTS      Timestamp   `json:"started_ts"`
+	DoneTS         Timestamp   `json:"done_ts"`
+	Duration       Duration    `json:"duration"`
+	Progress       int         `json:"progress"`
+	ETA            Duration    `json:"eta"`
+	From           string      `json:"from"`
+	To             string      `json:"to"`
+	NFiles         int         `json:"nfiles"`
+	NFilesDone     int         `json:"nfiles_done"`
+	TotalBytes     int         `json:"total_bytes"`
+	TotalBytesDone int         `json:"total_bytes_done"`
+	CurrBytes      int         `json:"curr_bytes"`
+	Rate           int         `json:"rate"`
+}
+
+// Finished reports whether the task will not make any more progress.
+func (t *FSTask) Finished() bool {
+	return t.State.IsTerminal()
+}
+
+// Succeeded reports whether the task completed without error.
+func (t *FSTask) Succeeded() bool {
+	return t.State == FS_TASK_STATE_DONE && (t.Error == "" || t.Error == FS_TASK_ERROR_NONE)
+}
+
+// Err returns why a finished task did not succeed, nil when it did or is
+// still running.
+func (t *FSTask) Err() error {
+	switch {
+	case !t.Finished() || t.Succeeded():
+		return nil
+	case t.Error == FS_TASK_ERROR_REPAIR_FAILED:
+		return ErrRepairFailed
+	case t.Error == "" || t.Error == FS_TASK_ERROR_NONE:
+		return fmt.Errorf("%s task failed", t.Type)
+	}
+	return fmt.Errorf("%s task failed: %s", t.Type, t.Error)
+}
+
+type RepairReq struct {
+	Src FilePath `json:"src"`
+}
+
+type ExtractReq struct {
+	Src           FilePath `json:"src"`
+	Dst           FilePath `json:"dst"`
+	Password      string   `json:"password,omitempty"`
+	DeleteArchive bool     `json:"delete_archive"`
+	Overwrite     bool     `json:"overwrite"`
+}
+
+// ExtractOptions tunes Extract, a nil value keeps the archive and does not
+// overwrite existing files.
+type ExtractOptions struct {
+	Password      string
+	DeleteArchive bool
+	Overwrite     bool
+}
+
+type RemoveReq struct {
+	Files []FilePath `json:"files"`
+}
+
+type MoveReq struct {
+	Files []FilePath `json:"files"`
+	Dst   FilePath   `json:"dst"`
+	Mode  string     `json:"mode"`
+}
+
+type RenameReq struct {
+	Src FilePath `json:"src"`
+	Dst string   `json:"dst"`
+}
+
+type HashReq struct {
+	Src      FilePath `json:"src"`
+	HashType string   `json:"hash_type"`
+}
+
+type MkdirReq struct {
+	Parent  FilePath `json:"parent"`
+	Dirname string   `json:"dirname"`
 }
 
 type FileInfo struct {
-	Path         string `json:"path"`
-	Name         string `json:"name"`
-	MimeType     string `json:"mimetype"`
-	Type         string `json:"type"`
-	Size         int    `json:"size"`
-	Modification int    `json:"modification"`
-	Index        int    `json:"index"`
-	Link         bool   `json:"link"`
-	Target       string `json:"target"`
-	Hidden       bool   `json:"hidden"`
-	FolderCount  int    `json:"foldercount"`
-	FileCount    int    `json:"filecount"`
-	Parent       string `json:"parent"`
+	Path         FilePath  `json:"path"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimetype"`
+	Type         FileType  `json:"type"`
+	Size         int       `json:"size"`
+	Modification Timestamp `json:"modification"`
+	Index        int       `json:"index"`
+	Link         bool      `json:"link"`
+	Target       FilePath  `json:"target"`
+	Hidden       bool      `json:"hidden"`
+	FolderCount  int       `json:"foldercount"`
+	FileCount    int       `json:"filecount"`
+	Parent       FilePath  `json:"parent"`
 }
 
+const UPLOAD_STATUS_AUTHORIZED = "authorized"
+const UPLOAD_STATUS_IN_PROGRESS = "in_progress"
+const UPLOAD_STATUS_DONE = "done"
+const UPLOAD_STATUS_FAILED = "failed"
+const UPLOAD_STATUS_CONFLICT = "conflict"
+const UPLOAD_STATUS_TIMEOUT = "timeout"
+const UPLOAD_STATUS_CANCELLED = "cancelled"
+
 type FileUpload struct {
-	ID         int    `json:"id"`
-	Size       int    `json:"size"`
-	Uploaded   int    `json:"uploaded"`
-	Status     string `json:"status"`
-	StartDate  int    `json:"start_date"`
-	LastUpdate int    `json:"last_update"`
-	UploadName string `json:"upload_name"`
-	Dirname    string `json:"dirname"`
+	ID         int       `json:"id"`
+	Size       int       `json:"size"`
+	Uploaded   int       `json:"uploaded"`
+	Status     string    `json:"status"`
+	StartDate  Timestamp `json:"start_date"`
+	LastUpdate Timestamp `json:"last_update"`
+	UploadName string    `json:"upload_name"`
+	Dirname    FilePath  `json:"dirname"`
 }
 
 type FileUploadStartAction struct {
 	WSRequest
-	Size     int    `json:"size"`
-	Dirname  string `json:"dirname"`
-	Filename string `json:"filename"`
-	Force    string `json:"force"`
+	Size     int      `json:"size"`
+	Dirname  FilePath `json:"dirname"`
+	Filename string   `json:"filename"`
+	Force    string   `json:"force,omitempty"`
 }
 
 type FileUploadChunkResult struct {
@@ -83,12 +230,12 @@ type FileUploadChunkResponse struct {
 }
 
 type ShareLink struct {
-	Token    string `json:"token,omitempty"`
-	Path     string `json:"path,omitempty"`
-	Name     string `json:"name,omitempty"`
-	Expire   int    `json:"expire"`
-	FullURL  string `json:"fullurl,omitempty"`
-	Internal int    `json:"internal"` // Undocumented
+	Token    string    `json:"token,omitempty"`
+	Path     FilePath  `json:"path,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Expire   Timestamp `json:"expire"`
+	FullURL  string    `json:"fullurl,omitempty"`
+	Internal int       `json:"internal"` // Undocumented
 }
 
 func EncodePath(path string) string {
@@ -111,6 +258,83 @@ var TasksEP = &Endpoint{
 	Url:  "fs/tasks/",
 }
 
+// TaskEP endpoint definition
+// Output: FSTask
+var TaskEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "fs/tasks/{{.id}}",
+}
+
+// DeleteTaskEP endpoint definition
+// Output: nil
+var DeleteTaskEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "fs/tasks/{{.id}}",
+}
+
+// TaskHashEP endpoint definition
+// Output: string
+var TaskHashEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "fs/tasks/{{.id}}/hash",
+}
+
+// RemoveEP endpoint definition
+// Output: FSTask
+var RemoveEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/rm/",
+	BodyRequired: true,
+}
+
+// MoveEP endpoint definition
+// Output: FSTask
+var MoveEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/mv/",
+	BodyRequired: true,
+}
+
+// RenameEP endpoint definition
+// Output: FileInfo
+var RenameEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/rename/",
+	BodyRequired: true,
+}
+
+// HashEP endpoint definition
+// Output: FSTask
+var HashEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/hash/",
+	BodyRequired: true,
+}
+
+// RepairEP endpoint definition
+// Output: FSTask
+var RepairEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/repair/",
+	BodyRequired: true,
+}
+
+// ExtractEP endpoint definition
+// Output: FSTask
+var ExtractEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/extract/",
+	BodyRequired: true,
+}
+
+// MkdirEP endpoint definition
+// Output: string
+var MkdirEP = &Endpoint{
+	Verb:         HTTP_METHOD_POST,
+	Url:          "fs/mkdir/",
+	BodyRequired: true,
+}
+
 var LsEP = &Endpoint{
 	Verb: HTTP_METHOD_GET,
 	Url:  "fs/ls/{{.path}}",
@@ -137,6 +361,10 @@ var ShareEP = &Endpoint{
 }
 
 func (c *Client) Ls(path string, onlyFolder, countSubFolder, removeHidden bool) (respFileInfo []FileInfo, err error) {
+	return c.lsContext(context.Background(), path, onlyFolder, countSubFolder, removeHidden)
+}
+
+func (c *Client) lsContext(ctx context.Context, path string, onlyFolder, countSubFolder, removeHidden bool) (respFileInfo []FileInfo, err error) {
 	defer panicAttack(&err)
 
 	queryParams := url.Values{}
@@ -148,7 +376,7 @@ func (c *Client) Ls(path string, onlyFolder, countSubFolder, removeHidden bool)
 		"path": EncodePath(path),
 	}
 
-	err = c.Query(LsEP).As(params).WithParams(queryParams).Do(&respFileInfo)
+	err = c.Query(LsEP).As(params).WithParams(queryParams).WithContext(ctx).Do(&respFileInfo)
 	checkErr(err)
 	return
 }
@@ -165,6 +393,27 @@ func (c *Client) Info(path string) (respFileInfo *FileInfo, err error) {
 	return
 }
 
+// Mkdir creates dirname inside parent and returns the path of the new folder.
+func (c *Client) Mkdir(parent, dirname string) (path string, err error) {
+	defer panicAttack(&err)
+
+	req := &MkdirReq{
+		Parent:  FilePath(parent),
+		Dirname: dirname,
+	}
+
+	var newPath FilePath
+	err = c.Query(MkdirEP).WithBody(req).Do(&newPath)
+	checkErr(err)
+
+	path = string(newPath)
+	return
+}
+
+// Dl starts the download of path. The body is throttled by the client
+// download limit, without a context: a read waiting for the limiter can not
+// be cancelled and closing the body only takes effect once it returns. Use
+// DlRange(ctx, path, 0, 0) to pass a context.
 func (c *Client) Dl(path string) (resp *http.Response, err error) {
 	defer panicAttack(&err)
 
@@ -174,135 +423,170 @@ func (c *Client) Dl(path string) (resp *http.Response, err error) {
 
 	resp, err = c.Query(DlEP).As(params).DoRequest()
 	checkErr(err)
+
+	c.limitBody(context.Background(), resp, nil)
 	return
 }
 
-func dispatchRecvError(ctx context.Context, entryCh <-chan *WSResponse, errorCh chan<- bool) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case resp := <-entryCh:
-			if !resp.Success {
-				errorCh <- true
-				return
-			}
-		}
-	}
-}
+func (c *Client) Task(id int) (task *FSTask, err error) {
+	defer panicAttack(&err)
 
-func uploadMsgReceiver(ctx context.Context, conn *websocket.Conn, dispatcher map[string]chan *WSResponse) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			var message []byte
-			if err := websocket.Message.Receive(conn, &message); err == nil {
-				resp := new(WSResponse)
-				err = json.Unmarshal(message, &resp)
-				checkErr(err)
-
-				if ch, ok := dispatcher[resp.Action]; ok {
-					ch <- resp
-				}
-			}
-		}
+	params := map[string]string{
+		"id": strconv.Itoa(id),
 	}
-}
 
-func sendFile(ctx context.Context, cancel context.CancelFunc, dataRecvCh <-chan *WSResponse, conn *websocket.Conn, path string, reqID int) {
-	f, err := os.Open(path)
+	task = new(FSTask)
+	err = c.Query(TaskEP).As(params).Do(task)
 	checkErr(err)
+	return
+}
 
-	defer f.Close()
+func (c *Client) DeleteTask(id int) (err error) {
+	defer panicAttack(&err)
 
-	errorCh := make(chan bool, 1)
-	defer close(errorCh)
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
 
-	go dispatchRecvError(ctx, dataRecvCh, errorCh)
+	err = c.Query(DeleteTaskEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
 
-	buf := make([]byte, 512000)
+// WaitTask polls the task until it is Finished.
+func (c *Client) WaitTask(ctx context.Context, id int) (task *FSTask, err error) {
+	defer panicAttack(&err)
 
-send_loop:
 	for {
+		task, err = c.Task(id)
+		checkErr(err)
+
+		if task.Finished() {
+			return
+		}
+
 		select {
 		case <-ctx.Done():
-			return
-		case <-errorCh:
-			cancel()
-			return
-		default:
-			n, err := f.Read(buf)
-			if err == io.EOF {
-				break send_loop
-			}
-			checkErr(err)
-
-			err = websocket.Message.Send(conn, buf[:n])
-			checkErr(err)
+			checkErr(ctx.Err())
+		case <-time.After(FS_TASK_POLL_INTERVAL):
 		}
 	}
+}
 
-	reqUploadFinalize := &WSRequest{
-		Action:    "upload_finalize",
-		RequestID: reqID,
+// Remove starts the recursive removal of paths.
+func (c *Client) Remove(paths ...string) (task *FSTask, err error) {
+	defer panicAttack(&err)
+
+	req := &RemoveReq{}
+	for _, path := range paths {
+		req.Files = append(req.Files, FilePath(path))
 	}
 
-	err = websocket.JSON.Send(conn, reqUploadFinalize)
+	task = new(FSTask)
+	err = c.Query(RemoveEP).WithBody(req).Do(task)
 	checkErr(err)
+	return
 }
 
-func (c *Client) Upload(path, destDir string) (err error) {
+// Move starts moving paths inside the dstDir folder, mode being one of the
+// FS_CONFLICT_* values.
+func (c *Client) Move(paths []string, dstDir, mode string) (task *FSTask, err error) {
 	defer panicAttack(&err)
 
-	conn, err := c.Query(UlEP).WS()
-	checkErr(err)
-	defer conn.Close()
+	req := &MoveReq{
+		Dst:  FilePath(dstDir),
+		Mode: mode,
+	}
+	for _, path := range paths {
+		req.Files = append(req.Files, FilePath(path))
+	}
 
-	fi, err := os.Stat(path)
+	task = new(FSTask)
+	err = c.Query(MoveEP).WithBody(req).Do(task)
 	checkErr(err)
+	return
+}
+
+// Rename renames the file at path to newName, in the same folder.
+func (c *Client) Rename(path, newName string) (info *FileInfo, err error) {
+	defer panicAttack(&err)
 
-	reqID := int(time.Now().Unix())
-
-	reqUploadStart := &FileUploadStartAction{
-		WSRequest: WSRequest{
-			Action:    "upload_start",
-			RequestID: reqID,
-		},
-		Size:     int(fi.Size()),
-		Dirname:  EncodePath(destDir),
-		Filename: fi.Name(),
-		Force:    "overwrite",
+	req := &RenameReq{
+		Src: FilePath(path),
+		Dst: newName,
 	}
 
-	dispatcher := map[string]chan *WSResponse{
-		"upload_start":    make(chan *WSResponse),
-		"upload_data":     make(chan *WSResponse),
-		"upload_finalize": make(chan *WSResponse),
+	info = new(FileInfo)
+	err = c.Query(RenameEP).WithBody(req).Do(info)
+	checkErr(err)
+	return
+}
+
+// Hash computes the hashType digest of the file at path on the box, waiting
+// for the underlying task which is then deleted.
+func (c *Client) Hash(ctx context.Context, path, hashType string) (hash string, err error) {
+	defer panicAttack(&err)
+
+	req := &HashReq{
+		Src:      FilePath(path),
+		HashType: hashType,
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	task := new(FSTask)
+	err = c.Query(HashEP).WithBody(req).Do(task)
+	checkErr(err)
+	defer c.DeleteTask(task.ID)
 
-	go uploadMsgReceiver(ctx, conn, dispatcher)
+	task, err = c.WaitTask(ctx, task.ID)
+	checkErr(err)
 
-	err = websocket.JSON.Send(conn, reqUploadStart)
+	if !task.Succeeded() {
+		checkErr(fmt.Errorf("hash of %s failed: %s", path, task.Error))
+	}
+
+	params := map[string]string{
+		"id": strconv.Itoa(task.ID),
+	}
+
+	err = c.Query(TaskHashEP).As(params).Do(&hash)
 	checkErr(err)
+	return
+}
 
-	resp := <-dispatcher["upload_start"]
-	if !resp.Success {
-		return errors.New(resp.Msg)
+// Repair starts a par2 repair of the files described by parFilePath. The
+// returned task can be polled with Task or WaitTask until it is Finished,
+// Err then returns ErrRepairFailed when the files could not be repaired.
+func (c *Client) Repair(parFilePath string) (task *FSTask, err error) {
+	defer panicAttack(&err)
+
+	req := &RepairReq{
+		Src: FilePath(parFilePath),
 	}
 
-	go sendFile(ctx, cancel, dispatcher["upload_data"], conn, path, reqID)
+	task = new(FSTask)
+	err = c.Query(RepairEP).WithBody(req).Do(task)
+	checkErr(err)
+	return
+}
 
-	select {
-	case <-ctx.Done():
-		checkErr(ctx.Err())
-	case <-dispatcher["upload_finalize"]:
+// Extract starts the extraction of the archive at src into the dst folder.
+func (c *Client) Extract(src, dst string, opts *ExtractOptions) (task *FSTask, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
 
+	req := &ExtractReq{
+		Src:           FilePath(src),
+		Dst:           FilePath(dst),
+		Password:      opts.Password,
+		DeleteArchive: opts.DeleteArchive,
+		Overwrite:     opts.Overwrite,
 	}
 
+	task = new(FSTask)
+	err = c.Query(ExtractEP).WithBody(req).Do(task)
+	checkErr(err)
 	return
 }
@@ -0,0 +1,252 @@
+package fbxapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+const RANGEHEADER = "Range"
+
+type DownloadProgressFunc func(downloaded, total int64)
+
+type DownloadOptions struct {
+	// Parallel is the number of ranges fetched at the same time, defaults to a
+	// single sequential request.
+	Parallel int
+	Progress DownloadProgressFunc
+	// RateLimit throttles this download, on top of the client download
+	// limit. It is shared by the parallel ranges.
+	RateLimit *RateLimiter
+}
+
+type downloadRange struct {
+	start int64
+	end   int64 // exclusive
+}
+
+// DlRange requests the bytes of path starting at offset, until end when it is
+// positive. The caller has to check the status code: a 200 instead of a 206
+// means the box sent the whole file.
+func (c *Client) DlRange(ctx context.Context, path string, offset, end int64) (resp *http.Response, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"path": EncodePath(path),
+	}
+
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if end > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, end-1)
+	}
+
+	resp, err = c.Query(DlEP).As(params).WithContext(ctx).WithHeader(RANGEHEADER, byteRange).DoRequest()
+	checkErr(err)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		checkErr(fmt.Errorf("unexpected status %s for %s", resp.Status, path))
+	}
+
+	c.limitBody(ctx, resp, nil)
+	return
+}
+
+// limitBody throttles the body of resp with the client download limit and
+// limit.
+func (c *Client) limitBody(ctx context.Context, resp *http.Response, limit *RateLimiter) {
+	downloadLimit, _ := c.limits()
+	body := newLimitedReader(ctx, resp.Body, downloadLimit, limit)
+	if body != io.Reader(resp.Body) {
+		resp.Body = limitedBody{body, resp.Body}
+	}
+}
+
+// progressCounter sums the bytes fetched by all the ranges, the progress
+// callback is never called concurrently.
+type progressCounter struct {
+	mutex    sync.Mutex
+	done     int64
+	total    int64
+	progress DownloadProgressFunc
+}
+
+func (p *progressCounter) add(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.done += n
+	if p.progress != nil {
+		p.progress(p.done, p.total)
+	}
+}
+
+// progressWriter counts the bytes written through it.
+type progressWriter struct {
+	w       io.Writer
+	counter *progressCounter
+}
+
+func (pw *progressWriter) Write(b []byte) (n int, err error) {
+	n, err = pw.w.Write(b)
+	pw.counter.add(int64(n))
+	return
+}
+
+// fetchRange appends r.start to r.end of remote to f, which must already hold
+// the bytes up to r.start.
+func (c *Client) fetchRange(ctx context.Context, remote string, f *os.File, r downloadRange, counter *progressCounter, limit *RateLimiter) (err error) {
+	defer panicAttack(&err)
+
+	resp, err := c.DlRange(ctx, remote, r.start, r.end)
+	checkErr(err)
+	defer resp.Body.Close()
+
+	body := newLimitedReader(ctx, resp.Body, limit)
+	if resp.StatusCode == http.StatusOK {
+		// range ignored, start over
+		counter.add(-r.start)
+		err = f.Truncate(0)
+		checkErr(err)
+		_, err = f.Seek(0, io.SeekStart)
+		checkErr(err)
+		body = io.LimitReader(body, r.end)
+	} else {
+		_, err = f.Seek(r.start, io.SeekStart)
+		checkErr(err)
+	}
+
+	_, err = io.Copy(&progressWriter{w: f, counter: counter}, body)
+	checkErr(err)
+	return
+}
+
+// fetchPart downloads r into its own part file, resuming from the size of an
+// existing one.
+func (c *Client) fetchPart(ctx context.Context, remote, partPath string, r downloadRange, counter *progressCounter, limit *RateLimiter) (err error) {
+	defer panicAttack(&err)
+
+	part, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0644)
+	checkErr(err)
+	defer part.Close()
+
+	fi, err := part.Stat()
+	checkErr(err)
+
+	done := fi.Size()
+	if done > r.end-r.start {
+		done = 0
+		err = part.Truncate(0)
+		checkErr(err)
+	}
+	counter.add(done)
+
+	if r.start+done < r.end {
+		resp, err := c.DlRange(ctx, remote, r.start+done, r.end)
+		checkErr(err)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusPartialContent {
+			checkErr(fmt.Errorf("%s does not support ranged downloads", remote))
+		}
+
+		_, err = part.Seek(done, io.SeekStart)
+		checkErr(err)
+		_, err = io.Copy(&progressWriter{w: part, counter: counter}, newLimitedReader(ctx, resp.Body, limit))
+		checkErr(err)
+	}
+	return
+}
+
+// DownloadToFile downloads remote into local. An existing local file is
+// considered as a partial download and completed with Range requests. With
+// opts.Parallel greater than one the missing bytes are split in as many ranges,
+// each one fetched in a local.<start>-<end>.part file which survives an
+// interruption and is appended to local once all of them are complete.
+func (c *Client) DownloadToFile(ctx context.Context, remote, local string, opts *DownloadOptions) (err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+
+	info, err := c.Info(remote)
+	checkErr(err)
+	size := int64(info.Size)
+
+	f, err := os.OpenFile(local, os.O_CREATE|os.O_RDWR, 0644)
+	checkErr(err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	checkErr(err)
+
+	offset := fi.Size()
+	if offset > size {
+		offset = 0
+		err = f.Truncate(0)
+		checkErr(err)
+	}
+
+	counter := &progressCounter{done: offset, total: size, progress: opts.Progress}
+
+	parallel := int64(opts.Parallel)
+	if parallel <= 1 || size-offset < parallel {
+		if offset < size {
+			err = c.fetchRange(ctx, remote, f, downloadRange{offset, size}, counter, opts.RateLimit)
+			checkErr(err)
+		}
+	} else {
+		var ranges []downloadRange
+		partSize := (size - offset) / parallel
+		for i := int64(0); i < parallel; i++ {
+			r := downloadRange{offset + i*partSize, offset + (i+1)*partSize}
+			if i == parallel-1 {
+				r.end = size
+			}
+			ranges = append(ranges, r)
+		}
+
+		partPath := func(r downloadRange) string {
+			return fmt.Sprintf("%s.%d-%d.part", local, r.start, r.end)
+		}
+
+		errs := make([]error, len(ranges))
+		var wg sync.WaitGroup
+		for i, r := range ranges {
+			wg.Add(1)
+			go func(i int, r downloadRange) {
+				defer wg.Done()
+				errs[i] = c.fetchPart(ctx, remote, partPath(r), r, counter, opts.RateLimit)
+			}(i, r)
+		}
+		wg.Wait()
+
+		for _, partErr := range errs {
+			checkErr(partErr)
+		}
+
+		_, err = f.Seek(offset, io.SeekStart)
+		checkErr(err)
+		for _, r := range ranges {
+			part, err := os.Open(partPath(r))
+			checkErr(err)
+			_, err = io.Copy(f, part)
+			part.Close()
+			checkErr(err)
+		}
+		for _, r := range ranges {
+			os.Remove(partPath(r))
+		}
+	}
+
+	fi, err = f.Stat()
+	checkErr(err)
+	if fi.Size() != size {
+		checkErr(fmt.Errorf("%s: size mismatch, got %d bytes instead of %d", local, fi.Size(), size))
+	}
+	return
+}
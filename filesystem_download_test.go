@@ -0,0 +1,45 @@
+package fbxapi
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testDownloadToFile(t *testing.T, opts *DownloadOptions) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	expected, err := ioutil.ReadFile("fixtures/lipsum.txt")
+	failOnError(t, err)
+
+	dir, err := ioutil.TempDir("", "fbxapi")
+	failOnError(t, err)
+	defer os.RemoveAll(dir)
+
+	// simulate an interrupted download
+	local := filepath.Join(dir, "lipsum.txt")
+	err = ioutil.WriteFile(local, expected[:100], 0644)
+	failOnError(t, err)
+
+	err = testClient.DownloadToFile(context.Background(), "/Disque dur/lipsum.txt", local, opts)
+	failOnError(t, err)
+
+	content, err := ioutil.ReadFile(local)
+	failOnError(t, err)
+
+	if !bytes.Equal(content, expected) {
+		t.Fail()
+	}
+}
+
+func TestDownloadToFile(t *testing.T) {
+	testDownloadToFile(t, nil)
+}
+
+func TestDownloadToFileParallel(t *testing.T) {
+	testDownloadToFile(t, &DownloadOptions{Parallel: 4})
+}
@@ -0,0 +1,197 @@
+package fbxapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// REMOTE_FILE_MAX_SKIP is the largest forward jump served by discarding bytes
+// of the current stream instead of issuing a new Range request.
+const REMOTE_FILE_MAX_SKIP = 256 * 1024
+
+// RemoteFile is a read only handle on a file stored on the box. Reads are
+// served by a single streamed response of the dl/ endpoint, a new Range
+// request is only issued when a Seek or ReadAt moves outside of it. ReadAt
+// may be called concurrently, the calls are serialized.
+type RemoteFile struct {
+	client *Client
+	path   string
+	info   *FileInfo
+	size   int64
+
+	mutex  sync.Mutex
+	offset int64
+	// body streams the file from bodyOffset
+	body       io.ReadCloser
+	bodyOffset int64
+	closed     bool
+}
+
+var _ io.ReadSeekCloser = (*RemoteFile)(nil)
+var _ io.ReaderAt = (*RemoteFile)(nil)
+
+var errWhence = errors.New("Seek: invalid whence")
+var errOffset = errors.New("Seek: invalid offset")
+
+// Open returns a handle to read the file at path without downloading it.
+func (c *Client) Open(path string) (file *RemoteFile, err error) {
+	defer panicAttack(&err)
+
+	info, err := c.Info(path)
+	checkErr(err)
+
+	if info.Type == FILE_TYPE_DIR {
+		checkErr(&os.PathError{Op: "open", Path: path, Err: errors.New("is a directory")})
+	}
+
+	file = &RemoteFile{
+		client: c,
+		path:   path,
+		info:   info,
+		size:   int64(info.Size),
+	}
+	return
+}
+
+func (f *RemoteFile) Stat() (*FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *RemoteFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	f.closeBody()
+	return nil
+}
+
+// closeBody drops the current stream, the mutex must be held.
+func (f *RemoteFile) closeBody() {
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+}
+
+// stream returns the body positioned at off, reusing the current one when off
+// is at or shortly after its position. The mutex must be held.
+func (f *RemoteFile) stream(off int64) (body io.Reader, err error) {
+	defer panicAttack(&err)
+
+	if f.body != nil && off >= f.bodyOffset && off-f.bodyOffset <= REMOTE_FILE_MAX_SKIP {
+		skipped, skipErr := io.CopyN(ioutil.Discard, f.body, off-f.bodyOffset)
+		f.bodyOffset += skipped
+		if skipErr == nil {
+			return f.body, nil
+		}
+	}
+	f.closeBody()
+
+	resp, err := f.client.DlRange(context.Background(), f.path, off, 0)
+	checkErr(err)
+
+	if resp.StatusCode == http.StatusOK {
+		// range ignored, skip to the requested offset
+		_, err = io.CopyN(ioutil.Discard, resp.Body, off)
+		if err != nil {
+			resp.Body.Close()
+			checkErr(err)
+		}
+	}
+
+	f.body = resp.Body
+	f.bodyOffset = off
+	return f.body, nil
+}
+
+// readAt reads from the stream at off, filling p when full is set. The mutex
+// must be held.
+func (f *RemoteFile) readAt(p []byte, off int64, full bool) (n int, err error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+
+	body, err := f.stream(off)
+	if err != nil {
+		return 0, err
+	}
+
+	if full {
+		n, err = io.ReadFull(body, p)
+	} else {
+		n, err = body.Read(p)
+	}
+	f.bodyOffset += int64(n)
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = io.EOF
+		if off+int64(n) < f.size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil && err != io.EOF {
+		f.closeBody()
+	}
+	return
+}
+
+func (f *RemoteFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errOffset
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.readAt(p, off, true)
+}
+
+func (f *RemoteFile) Read(p []byte) (n int, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	n, err = f.readAt(p, f.offset, false)
+	f.offset += int64(n)
+
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return
+}
+
+func (f *RemoteFile) Seek(offset int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, errWhence
+	}
+	if offset < 0 {
+		return 0, errOffset
+	}
+
+	f.offset = offset
+	return offset, nil
+}
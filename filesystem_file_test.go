@@ -0,0 +1,41 @@
+package fbxapi
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	expected, err := ioutil.ReadFile("fixtures/lipsum.txt")
+	failOnError(t, err)
+
+	f, err := testClient.Open("/Disque dur/lipsum.txt")
+	failOnError(t, err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	failOnError(t, err)
+	if info.Size != len(expected) {
+		t.Fatalf("size %d instead of %d", info.Size, len(expected))
+	}
+
+	buf := make([]byte, 10)
+	_, err = f.ReadAt(buf, 42)
+	failOnError(t, err)
+	if !bytes.Equal(buf, expected[42:52]) {
+		t.Fail()
+	}
+
+	_, err = f.Seek(-10, io.SeekEnd)
+	failOnError(t, err)
+	tail, err := ioutil.ReadAll(f)
+	failOnError(t, err)
+	if !bytes.Equal(tail, expected[len(expected)-10:]) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,199 @@
+package fbxapi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// FS exposes the box storage found under a root folder as an fs.FS, so it can
+// be used with fs.WalkDir, fs.Glob, http.FS or template.ParseFS.
+type FS struct {
+	client *Client
+	root   string
+}
+
+var _ fs.ReadDirFS = (*FS)(nil)
+var _ fs.StatFS = (*FS)(nil)
+
+func (c *Client) FS(root string) *FS {
+	return &FS{
+		client: c,
+		root:   root,
+	}
+}
+
+func (fsys *FS) remotePath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(fsys.root, name), nil
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	remote, err := fsys.remotePath("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fsys.client.Info(remote)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.Type == FILE_TYPE_DIR {
+		return &fsDir{fsys: fsys, name: name, info: info}, nil
+	}
+
+	f, err := fsys.client.Open(remote)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{RemoteFile: f, name: name}, nil
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	remote, err := fsys.remotePath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fsys.client.Info(remote)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return &fsFileInfo{info: info, name: path.Base(name)}, nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	remote, err := fsys.remotePath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := fsys.client.Ls(remote, false, false, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for i := range infos {
+		if infos[i].Name == "." || infos[i].Name == ".." {
+			continue
+		}
+		fi := &fsFileInfo{info: &infos[i], name: infos[i].Name}
+		entries = append(entries, fs.FileInfoToDirEntry(fi))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// FSFileInfo returns info as an fs.FileInfo, its Sys method returning info.
+func (info *FileInfo) FSFileInfo() fs.FileInfo {
+	return &fsFileInfo{info: info, name: info.Name}
+}
+
+// fsFileInfo maps a FileInfo to an fs.FileInfo, Sys returns the *FileInfo.
+type fsFileInfo struct {
+	info *FileInfo
+	name string
+}
+
+func (fi *fsFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fsFileInfo) Size() int64 {
+	return int64(fi.info.Size)
+}
+
+func (fi *fsFileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(0644)
+	if fi.info.Type == FILE_TYPE_DIR {
+		mode = fs.ModeDir | 0755
+	}
+	if fi.info.Link {
+		mode |= fs.ModeSymlink
+	}
+	return mode
+}
+
+func (fi *fsFileInfo) ModTime() time.Time {
+	return fi.info.Modification.Time
+}
+
+func (fi *fsFileInfo) IsDir() bool {
+	return fi.info.Type == FILE_TYPE_DIR
+}
+
+func (fi *fsFileInfo) Sys() interface{} {
+	return fi.info
+}
+
+type fsFile struct {
+	*RemoteFile
+	name string
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{info: f.info, name: path.Base(f.name)}, nil
+}
+
+type fsDir struct {
+	fsys    *FS
+	name    string
+	info    *FileInfo
+	entries []fs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{info: d.info, name: path.Base(d.name)}, nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) Close() error {
+	if d.closed {
+		return os.ErrClosed
+	}
+	d.closed = true
+	return nil
+}
+
+func (d *fsDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	if d.closed {
+		return nil, os.ErrClosed
+	}
+
+	if !d.listed {
+		d.entries, err = d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.listed = true
+	}
+
+	if n <= 0 {
+		entries, d.entries = d.entries, nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries, d.entries = d.entries[:n], d.entries[n:]
+	return entries, nil
+}
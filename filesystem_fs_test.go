@@ -0,0 +1,42 @@
+package fbxapi
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+)
+
+func TestFS(t *testing.T) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	expected, err := ioutil.ReadFile("fixtures/lipsum.txt")
+	failOnError(t, err)
+
+	fsys := testClient.FS("/Disque dur")
+
+	fi, err := fs.Stat(fsys, "lipsum.txt")
+	failOnError(t, err)
+	if fi.IsDir() || fi.Size() != int64(len(expected)) {
+		t.Fail()
+	}
+
+	content, err := fs.ReadFile(fsys, "lipsum.txt")
+	failOnError(t, err)
+	if !bytes.Equal(content, expected) {
+		t.Fail()
+	}
+
+	matches, err := fs.Glob(fsys, "*.txt")
+	failOnError(t, err)
+	if !isIn("lipsum.txt", matches) {
+		t.Fail()
+	}
+
+	_, err = fs.Stat(fsys, "does-not-exist.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,454 @@
+package fbxapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const TRANSFER_DIRECTION_UPLOAD = "upload"
+const TRANSFER_DIRECTION_DOWNLOAD = "download"
+
+const TRANSFER_STATE_PENDING = "pending"
+const TRANSFER_STATE_RUNNING = "running"
+const TRANSFER_STATE_DONE = "done"
+const TRANSFER_STATE_FAILED = "failed"
+
+const DEFAULT_QUEUE_CONCURRENCY = 2
+const DEFAULT_QUEUE_MAX_ATTEMPTS = 5
+const DEFAULT_QUEUE_RETRY_DELAY = 5 * time.Second
+
+// QUEUE_MAX_RETRY_DELAY caps the exponential backoff between two attempts.
+const QUEUE_MAX_RETRY_DELAY = 10 * time.Minute
+
+// QUEUE_SAVE_INTERVAL is the minimum delay between two saves caused by
+// progress only, state changes are saved immediately.
+const QUEUE_SAVE_INTERVAL = 5 * time.Second
+
+// Transfer is a job of a TransferQueue. Remote is the full path of the file
+// on the box. Offset only reports the progress: downloads resume from the
+// size of the local file and uploads from what the box already holds.
+type Transfer struct {
+	ID        int    `json:"id"`
+	Direction string `json:"direction"`
+	Local     string `json:"local"`
+	Remote    string `json:"remote"`
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	State     string `json:"state"`
+	// Started is set once an attempt began, the destination may then hold
+	// a partial file to resume. Failed uploads keep their partial file on
+	// the box for that purpose.
+	Started   bool      `json:"started"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	NextTry   time.Time `json:"next_try,omitempty"`
+	Added     time.Time `json:"added"`
+	Completed time.Time `json:"completed,omitempty"`
+}
+
+type QueueOptions struct {
+	// Concurrency is the number of transfers run at the same time, defaults
+	// to DEFAULT_QUEUE_CONCURRENCY.
+	Concurrency int
+	// MaxAttempts is the number of tries before a transfer is failed,
+	// defaults to DEFAULT_QUEUE_MAX_ATTEMPTS.
+	MaxAttempts int
+	// RetryDelay is the delay before the first retry, doubled after each
+	// failure. Defaults to DEFAULT_QUEUE_RETRY_DELAY.
+	RetryDelay time.Duration
+	// RateLimit throttles all the transfers of the queue.
+	RateLimit *RateLimiter
+}
+
+// queueFile is the content of the file backing a TransferQueue.
+type queueFile struct {
+	NextID    int         `json:"next_id"`
+	Transfers []*Transfer `json:"transfers"`
+}
+
+// TransferQueue runs uploads and downloads, keeping track of them in a local
+// file so an interrupted process picks them up where they stopped: downloads
+// continue from the size of the local file, uploads ask the box to resume.
+type TransferQueue struct {
+	client   *Client
+	file     string
+	opts     QueueOptions
+	mutex    sync.Mutex
+	state    queueFile
+	lastSave time.Time
+	err      error
+	wake     chan struct{}
+}
+
+// OpenTransferQueue loads the queue saved in file, or starts an empty one
+// when it does not exist. Transfers left running by a previous process are
+// pending again and resume on their next attempt.
+func (c *Client) OpenTransferQueue(file string, opts *QueueOptions) (q *TransferQueue, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &QueueOptions{}
+	}
+
+	q = &TransferQueue{
+		client: c,
+		file:   file,
+		opts:   *opts,
+		wake:   make(chan struct{}, 1),
+	}
+	if q.opts.Concurrency <= 0 {
+		q.opts.Concurrency = DEFAULT_QUEUE_CONCURRENCY
+	}
+	if q.opts.MaxAttempts <= 0 {
+		q.opts.MaxAttempts = DEFAULT_QUEUE_MAX_ATTEMPTS
+	}
+	if q.opts.RetryDelay <= 0 {
+		q.opts.RetryDelay = DEFAULT_QUEUE_RETRY_DELAY
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	checkErr(err)
+
+	err = json.Unmarshal(data, &q.state)
+	checkErr(err)
+
+	for _, t := range q.state.Transfers {
+		if t.State == TRANSFER_STATE_RUNNING {
+			t.State = TRANSFER_STATE_PENDING
+		}
+	}
+	return
+}
+
+// save writes the queue to its file, through a temporary file so a crash
+// never leaves it truncated. The mutex must be held.
+func (q *TransferQueue) save() error {
+	data, err := json.MarshalIndent(&q.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := q.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, q.file); err != nil {
+		return err
+	}
+
+	q.lastSave = time.Now()
+	return nil
+}
+
+func (q *TransferQueue) add(direction, local, remote string) (transfer Transfer, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.state.NextID++
+	t := &Transfer{
+		ID:        q.state.NextID,
+		Direction: direction,
+		Local:     local,
+		Remote:    remote,
+		State:     TRANSFER_STATE_PENDING,
+		Added:     time.Now(),
+	}
+	q.state.Transfers = append(q.state.Transfers, t)
+
+	if err = q.save(); err != nil {
+		return
+	}
+	q.signal()
+	return *t, nil
+}
+
+// AddUpload queues the upload of the local file to the remote path.
+func (q *TransferQueue) AddUpload(local, remote string) (Transfer, error) {
+	return q.add(TRANSFER_DIRECTION_UPLOAD, local, remote)
+}
+
+// AddDownload queues the download of the remote file to the local path.
+func (q *TransferQueue) AddDownload(remote, local string) (Transfer, error) {
+	return q.add(TRANSFER_DIRECTION_DOWNLOAD, local, remote)
+}
+
+// Transfers returns a copy of every transfer of the queue.
+func (q *TransferQueue) Transfers() (transfers []Transfer) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, t := range q.state.Transfers {
+		transfers = append(transfers, *t)
+	}
+	return
+}
+
+// Retry puts the failed transfers back in the queue with a fresh attempt
+// count.
+func (q *TransferQueue) Retry() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, t := range q.state.Transfers {
+		if t.State == TRANSFER_STATE_FAILED {
+			t.State = TRANSFER_STATE_PENDING
+			t.Attempts = 0
+			t.NextTry = time.Time{}
+		}
+	}
+	q.signal()
+	return q.save()
+}
+
+// Prune forgets the completed transfers.
+func (q *TransferQueue) Prune() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var kept []*Transfer
+	for _, t := range q.state.Transfers {
+		if t.State != TRANSFER_STATE_DONE {
+			kept = append(kept, t)
+		}
+	}
+	q.state.Transfers = kept
+	return q.save()
+}
+
+func (q *TransferQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next marks the first pending transfer due for an attempt as running. When
+// there is none, wait is the delay until the next retry and active tells
+// whether any transfer is still pending or running.
+func (q *TransferQueue) next() (t *Transfer, wait time.Duration, active bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	wait = QUEUE_MAX_RETRY_DELAY
+	for _, candidate := range q.state.Transfers {
+		switch candidate.State {
+		case TRANSFER_STATE_RUNNING:
+			active = true
+		case TRANSFER_STATE_PENDING:
+			active = true
+			if !candidate.NextTry.After(now) {
+				candidate.State = TRANSFER_STATE_RUNNING
+				return candidate, 0, true
+			}
+			if delay := candidate.NextTry.Sub(now); delay < wait {
+				wait = delay
+			}
+		}
+	}
+	return
+}
+
+// backoff returns the delay before the next attempt of a transfer which
+// failed attempts times.
+func (q *TransferQueue) backoff(attempts int) time.Duration {
+	delay := q.opts.RetryDelay
+	for i := 1; i < attempts && delay < QUEUE_MAX_RETRY_DELAY; i++ {
+		delay *= 2
+	}
+	if delay > QUEUE_MAX_RETRY_DELAY {
+		delay = QUEUE_MAX_RETRY_DELAY
+	}
+	return delay
+}
+
+// progress records the offset of a running transfer, saving the queue at
+// most every QUEUE_SAVE_INTERVAL.
+func (q *TransferQueue) progress(t *Transfer, offset, size int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	t.Offset, t.Size = offset, size
+	if time.Since(q.lastSave) >= QUEUE_SAVE_INTERVAL {
+		if err := q.save(); err != nil && q.err == nil {
+			q.err = err
+		}
+	}
+}
+
+func (q *TransferQueue) upload(ctx context.Context, t *Transfer, resume bool) (err error) {
+	defer panicAttack(&err)
+
+	f, err := os.Open(t.Local)
+	checkErr(err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	checkErr(err)
+
+	// a previous attempt, maybe of a previous process, may have left a
+	// partial file on the box
+	conflict := UPLOAD_CONFLICT_OVERWRITE
+	if resume {
+		conflict = UPLOAD_CONFLICT_RESUME
+	}
+
+	opts := &UploadOptions{
+		Conflict:    conflict,
+		KeepPartial: true,
+		RateLimit:   q.opts.RateLimit,
+		Progress: func(uploaded, total int64) {
+			q.progress(t, uploaded, total)
+		},
+	}
+
+	destDir, name := path.Split(t.Remote)
+	err = q.client.UploadReader(ctx, f, fi.Size(), destDir, name, opts)
+	checkErr(err)
+
+	q.progress(t, fi.Size(), fi.Size())
+	return
+}
+
+func (q *TransferQueue) download(ctx context.Context, t *Transfer, resume bool) (err error) {
+	defer panicAttack(&err)
+
+	// DownloadToFile continues any existing local file, which is only a
+	// partial download when an earlier attempt wrote it
+	if !resume {
+		if err = os.Remove(t.Local); os.IsNotExist(err) {
+			err = nil
+		}
+		checkErr(err)
+	}
+
+	opts := &DownloadOptions{
+		RateLimit: q.opts.RateLimit,
+		Progress: func(downloaded, total int64) {
+			q.progress(t, downloaded, total)
+		},
+	}
+
+	err = q.client.DownloadToFile(ctx, t.Remote, t.Local, opts)
+	checkErr(err)
+	return
+}
+
+// start records that an attempt of t begins, resume telling whether an
+// earlier one did.
+func (q *TransferQueue) start(t *Transfer) (resume bool, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	resume, t.Started = t.Started, true
+	if !resume {
+		err = q.save()
+	}
+	return
+}
+
+// run performs one attempt of t and records its outcome.
+func (q *TransferQueue) run(ctx context.Context, t *Transfer) {
+	resume, err := q.start(t)
+	switch {
+	case err != nil:
+	case t.Direction == TRANSFER_DIRECTION_UPLOAD:
+		err = q.upload(ctx, t, resume)
+	case t.Direction == TRANSFER_DIRECTION_DOWNLOAD:
+		err = q.download(ctx, t, resume)
+	default:
+		err = fmt.Errorf("unknown transfer direction %q", t.Direction)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	switch {
+	case err == nil:
+		t.State = TRANSFER_STATE_DONE
+		t.Error = ""
+		t.Completed = time.Now()
+	case ctx.Err() != nil:
+		// interrupted, not a failure of the transfer
+		t.State = TRANSFER_STATE_PENDING
+	default:
+		t.Attempts++
+		t.Error = err.Error()
+		t.State = TRANSFER_STATE_PENDING
+		t.NextTry = time.Now().Add(q.backoff(t.Attempts))
+		if t.Attempts >= q.opts.MaxAttempts {
+			t.State = TRANSFER_STATE_FAILED
+		}
+	}
+
+	if saveErr := q.save(); saveErr != nil && q.err == nil {
+		q.err = saveErr
+	}
+	q.signal()
+}
+
+func (q *TransferQueue) saveErr() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.err
+}
+
+// Run processes the queue until every transfer is done or failed, or ctx is
+// done. Transfers added meanwhile are processed too. It returns an error
+// when the queue can not be saved anymore, failed transfers are reported
+// by Transfers.
+func (q *TransferQueue) Run(ctx context.Context) (err error) {
+	jobs := make(chan *Transfer)
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				q.run(ctx, t)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		if err = q.saveErr(); err != nil {
+			return
+		}
+
+		t, wait, active := q.next()
+		if t != nil {
+			select {
+			case jobs <- t:
+				continue
+			case <-ctx.Done():
+				q.mutex.Lock()
+				t.State = TRANSFER_STATE_PENDING
+				q.mutex.Unlock()
+				return ctx.Err()
+			}
+		}
+		if !active {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.wake:
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package fbxapi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransferQueuePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fbxqueue")
+	failOnError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "queue.json")
+
+	q, err := new(Client).OpenTransferQueue(file, nil)
+	failOnError(t, err)
+
+	_, err = q.AddUpload("/tmp/a.bin", "/Disque dur/a.bin")
+	failOnError(t, err)
+	down, err := q.AddDownload("/Disque dur/b.bin", "/tmp/b.bin")
+	failOnError(t, err)
+
+	// simulate a process killed during the first transfer, before any
+	// progress was saved
+	running, _, _ := q.next()
+	resume, err := q.start(running)
+	failOnError(t, err)
+	if resume {
+		t.Fatal("first attempt resumes")
+	}
+	q.progress(running, 1024, 4096)
+	q.mutex.Lock()
+	failOnError(t, q.save())
+	q.mutex.Unlock()
+
+	q, err = new(Client).OpenTransferQueue(file, nil)
+	failOnError(t, err)
+
+	transfers := q.Transfers()
+	if len(transfers) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(transfers))
+	}
+	for _, transfer := range transfers {
+		if transfer.State != TRANSFER_STATE_PENDING {
+			t.Errorf("transfer %d is %s after reload", transfer.ID, transfer.State)
+		}
+	}
+	if transfers[0].Offset != 1024 || transfers[0].Direction != TRANSFER_DIRECTION_UPLOAD {
+		t.Errorf("unexpected reloaded transfer %+v", transfers[0])
+	}
+
+	running, _, _ = q.next()
+	resume, err = q.start(running)
+	failOnError(t, err)
+	if running.ID != transfers[0].ID || !resume {
+		t.Fatal("interrupted transfer not resumed")
+	}
+
+	added, err := q.AddUpload("/tmp/c.bin", "/Disque dur/c.bin")
+	failOnError(t, err)
+	if added.ID <= down.ID {
+		t.Fatalf("transfer id %d reused", added.ID)
+	}
+}
+
+func TestTransferQueueBackoff(t *testing.T) {
+	q := &TransferQueue{opts: QueueOptions{RetryDelay: time.Second}}
+
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		50: QUEUE_MAX_RETRY_DELAY,
+	} {
+		if got := q.backoff(attempts); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempts, want, got)
+		}
+	}
+}
+
+func TestTransferQueueFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fbxqueue")
+	failOnError(t, err)
+	defer os.RemoveAll(dir)
+
+	q, err := new(Client).OpenTransferQueue(filepath.Join(dir, "queue.json"), &QueueOptions{
+		MaxAttempts: 2,
+		RetryDelay:  time.Millisecond,
+	})
+	failOnError(t, err)
+
+	// the local file is missing, every attempt fails before reaching the box
+	_, err = q.AddUpload(filepath.Join(dir, "missing"), "/Disque dur/missing")
+	failOnError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	failOnError(t, q.Run(ctx))
+
+	transfer := q.Transfers()[0]
+	if transfer.State != TRANSFER_STATE_FAILED || transfer.Attempts != 2 || transfer.Error == "" {
+		t.Fatalf("unexpected transfer %+v", transfer)
+	}
+
+	failOnError(t, q.Retry())
+	if transfer = q.Transfers()[0]; transfer.State != TRANSFER_STATE_PENDING || transfer.Attempts != 0 {
+		t.Fatalf("transfer not retried %+v", transfer)
+	}
+}
+
+func TestTransferQueueStaleDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fbxqueue")
+	failOnError(t, err)
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "b.bin")
+	failOnError(t, ioutil.WriteFile(local, []byte("stale content"), 0644))
+
+	q, err := new(Client).OpenTransferQueue(filepath.Join(dir, "queue.json"), nil)
+	failOnError(t, err)
+	transfer := &Transfer{Direction: TRANSFER_DIRECTION_DOWNLOAD, Local: local, Remote: "/Disque dur/b.bin"}
+
+	// the client has no session, the download itself fails
+	if err = q.download(context.Background(), transfer, true); err == nil {
+		t.Fatal("download without a session succeeded")
+	}
+	if _, err = os.Stat(local); err != nil {
+		t.Fatal("partial download removed on resume")
+	}
+
+	if err = q.download(context.Background(), transfer, false); err == nil {
+		t.Fatal("download without a session succeeded")
+	}
+	if _, err = os.Stat(local); !os.IsNotExist(err) {
+		t.Fatal("stale local file kept on a first attempt")
+	}
+}
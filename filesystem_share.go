@@ -0,0 +1,116 @@
+package fbxapi
+
+import (
+	"time"
+)
+
+// ShareLinksEP endpoint definition
+// Output: []ShareLink
+var ShareLinksEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "share_link/",
+}
+
+// ShareLinkEP endpoint definition
+// Output: ShareLink
+var ShareLinkEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "share_link/{{.token}}",
+}
+
+// DeleteShareLinkEP endpoint definition
+// Output: nil
+var DeleteShareLinkEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "share_link/{{.token}}",
+}
+
+// ExpireTime returns the expiry date of the link, the zero time when it never
+// expires.
+func (link *ShareLink) ExpireTime() time.Time {
+	return link.Expire.Time
+}
+
+func (link *ShareLink) Expired() bool {
+	return !link.Expire.IsZero() && time.Now().After(link.Expire.Time)
+}
+
+// CreateShareLink creates a public link to path, valid until expire or
+// forever when expire is the zero time.
+func (c *Client) CreateShareLink(path string, expire time.Time) (link *ShareLink, err error) {
+	defer panicAttack(&err)
+
+	req := &ShareLink{
+		Path:   FilePath(path),
+		Expire: NewTimestamp(expire),
+	}
+
+	link = new(ShareLink)
+	err = c.Query(ShareEP).WithBody(req).Do(link)
+	checkErr(err)
+	return
+}
+
+// CreateShareLinkFor creates a public link to path valid for d, or forever
+// when d is zero.
+func (c *Client) CreateShareLinkFor(path string, d time.Duration) (*ShareLink, error) {
+	var expire time.Time
+	if d > 0 {
+		expire = time.Now().Add(d)
+	}
+	return c.CreateShareLink(path, expire)
+}
+
+// ShareLinks lists the active links.
+func (c *Client) ShareLinks() (links []ShareLink, err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(ShareLinksEP).Do(&links)
+	checkErr(err)
+	return
+}
+
+func (c *Client) GetShareLink(token string) (link *ShareLink, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"token": token,
+	}
+
+	link = new(ShareLink)
+	err = c.Query(ShareLinkEP).As(params).Do(link)
+	checkErr(err)
+	return
+}
+
+// DeleteShareLink revokes the link, it stops working immediately.
+func (c *Client) DeleteShareLink(token string) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"token": token,
+	}
+
+	err = c.Query(DeleteShareLinkEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// CleanShareLinks revokes every link for which match returns true and returns
+// them. It stops at the first failure.
+func (c *Client) CleanShareLinks(match func(link *ShareLink) bool) (deleted []ShareLink, err error) {
+	defer panicAttack(&err)
+
+	links, err := c.ShareLinks()
+	checkErr(err)
+
+	for i := range links {
+		if !match(&links[i]) {
+			continue
+		}
+		err = c.DeleteShareLink(links[i].Token)
+		checkErr(err)
+		deleted = append(deleted, links[i])
+	}
+	return
+}
@@ -0,0 +1,35 @@
+package fbxapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShareLinks(t *testing.T) {
+	var data []ShareLink
+	EndpointTester(t, ShareLinksEP, &data, nil, nil)
+}
+
+func TestShareLinkLifecycle(t *testing.T) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	link, err := testClient.CreateShareLinkFor("/Disque dur/lipsum.txt", time.Hour)
+	failOnError(t, err)
+
+	if link.Path != "/Disque dur/lipsum.txt" || link.Expired() {
+		t.Fatalf("unexpected link %#v", link)
+	}
+
+	link, err = testClient.GetShareLink(link.Token)
+	failOnError(t, err)
+
+	deleted, err := testClient.CleanShareLinks(func(l *ShareLink) bool {
+		return l.Token == link.Token
+	})
+	failOnError(t, err)
+
+	if len(deleted) != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,522 @@
+package fbxapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SYNC_DIRECTION_PUSH = "push"
+const SYNC_DIRECTION_PULL = "pull"
+const SYNC_DIRECTION_BOTH = "both"
+
+const SYNC_OP_MKDIR_REMOTE = "mkdir_remote"
+const SYNC_OP_MKDIR_LOCAL = "mkdir_local"
+const SYNC_OP_UPLOAD = "upload"
+const SYNC_OP_DOWNLOAD = "download"
+const SYNC_OP_DELETE_REMOTE = "delete_remote"
+const SYNC_OP_DELETE_LOCAL = "delete_local"
+
+// SYNC_OP_CONFLICT marks a path which is a file on one side and a folder on
+// the other, it is reported but never applied.
+const SYNC_OP_CONFLICT = "conflict"
+
+const syncTmpSuffix = ".fbxsync"
+
+type SyncOptions struct {
+	// Direction is one of the SYNC_DIRECTION_*, defaults to
+	// SYNC_DIRECTION_PUSH.
+	Direction string
+	// DeleteExtraneous removes the files missing from the source side. It is
+	// ignored for SYNC_DIRECTION_BOTH as there is no source side.
+	DeleteExtraneous bool
+	// DryRun only computes the plan.
+	DryRun bool
+	// Hash compares the sha256 of files having the same size, by default they
+	// are compared on their modification date.
+	Hash bool
+	// Include and Exclude filter the files as in UploadDirOptions.
+	Include []string
+	Exclude []string
+	// Concurrency is the number of transfers run at the same time, defaults
+	// to DEFAULT_UPLOAD_CONCURRENCY.
+	Concurrency int
+}
+
+type SyncAction struct {
+	Op     string
+	Local  string
+	Remote string
+	Size   int64
+	Reason string
+	// Err is set by ApplySync when the action failed.
+	Err error
+}
+
+type SyncPlan struct {
+	LocalDir  string
+	RemoteDir string
+	Actions   []SyncAction
+}
+
+type syncEntry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+func (plan *SyncPlan) String() string {
+	var b strings.Builder
+	for _, action := range plan.Actions {
+		fmt.Fprintf(&b, "%-14s %s <-> %s (%s)\n", action.Op, action.Local, action.Remote, action.Reason)
+	}
+	return b.String()
+}
+
+// localTree lists the local files below localDir, exists is false when
+// localDir itself is missing.
+func (c *Client) localTree(localDir string, opts *SyncOptions) (entries map[string]*syncEntry, exists bool, err error) {
+	entries = make(map[string]*syncEntry)
+	exists = true
+	err = filepath.Walk(localDir, func(localPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if localPath == localDir && os.IsNotExist(err) {
+				exists = false
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, localPath)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if strings.HasSuffix(rel, syncTmpSuffix) {
+			return nil
+		}
+		if matchAny(opts.Exclude, rel) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.IsDir() && !fi.Mode().IsRegular() {
+			return nil
+		}
+		if !fi.IsDir() && len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+
+		entries[rel] = &syncEntry{isDir: fi.IsDir(), size: fi.Size(), modTime: fi.ModTime()}
+		return nil
+	})
+	return
+}
+
+// remoteTree lists the remote files below remoteDir, exists is false when
+// remoteDir itself is missing.
+func (c *Client) remoteTree(ctx context.Context, remoteDir string, opts *SyncOptions) (entries map[string]*syncEntry, exists bool, err error) {
+	entries = make(map[string]*syncEntry)
+	exists = true
+	walkOpts := &WalkOptions{IncludeHidden: true}
+	err = c.WalkWithOptions(ctx, remoteDir, walkOpts, func(remotePath string, info *FileInfo, err error) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(remotePath, path.Clean(remoteDir)), "/")
+		if err != nil {
+			if rel == "" && errors.Is(err, os.ErrNotExist) {
+				exists = false
+				return nil
+			}
+			return err
+		}
+		if rel == "" {
+			return nil
+		}
+
+		isDir := info.Type == FILE_TYPE_DIR
+		if matchAny(opts.Exclude, rel) {
+			if isDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isDir && len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+
+		entries[rel] = &syncEntry{
+			isDir:   isDir,
+			size:    int64(info.Size),
+			modTime: info.Modification.Time,
+		}
+		return nil
+	})
+	return
+}
+
+// missingDirs lists dir and its ancestors which do not exist on the box, the
+// top most first.
+func (c *Client) missingDirs(dir string) (missing []string, err error) {
+	for dir = path.Clean(dir); dir != "/"; dir = path.Dir(dir) {
+		if _, err = c.Info(dir); err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		missing = append([]string{dir}, missing...)
+	}
+	return missing, nil
+}
+
+func hashLocalFile(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// changed tells whether two files of the same path differ, and which side is
+// the most recent one.
+func (c *Client) changed(ctx context.Context, localPath, remotePath string, local, remote *syncEntry, opts *SyncOptions) (changed bool, localNewer bool, reason string, err error) {
+	localNewer = local.modTime.Truncate(time.Second).After(remote.modTime)
+
+	if local.size != remote.size {
+		return true, localNewer, "size differs", nil
+	}
+
+	if opts.Hash {
+		localHash, err := hashLocalFile(localPath)
+		if err != nil {
+			return false, localNewer, "", err
+		}
+		remoteHash, err := c.Hash(ctx, remotePath, HASH_TYPE_SHA256)
+		if err != nil {
+			return false, localNewer, "", err
+		}
+		return localHash != remoteHash, localNewer, "hash differs", nil
+	}
+
+	// uploaded files get the upload date, a more recent remote file is thus
+	// expected after a push and is only meaningful for a pull. Both ways, it
+	// is taken as the result of the previous push: Hash catches remote edits
+	// keeping the size.
+	if opts.Direction == SYNC_DIRECTION_PULL {
+		remoteNewer := remote.modTime.After(local.modTime.Truncate(time.Second))
+		return remoteNewer, false, "remote is newer", nil
+	}
+	return localNewer, true, "local is newer", nil
+}
+
+// underDeleted tells whether one of the folders containing rel is deleted.
+func underDeleted(rel string, deletedDirs map[string]bool) bool {
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if deletedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanSync compares localDir and remoteDir and lists the actions needed to
+// bring them in sync, without applying any of them.
+func (c *Client) PlanSync(ctx context.Context, localDir, remoteDir string, opts *SyncOptions) (plan *SyncPlan, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if opts.Direction == "" {
+		defaults := *opts
+		defaults.Direction = SYNC_DIRECTION_PUSH
+		opts = &defaults
+	}
+
+	locals, localExists, err := c.localTree(localDir, opts)
+	checkErr(err)
+	remotes, remoteExists, err := c.remoteTree(ctx, remoteDir, opts)
+	checkErr(err)
+
+	var rels []string
+	for rel := range locals {
+		rels = append(rels, rel)
+	}
+	for rel := range remotes {
+		if _, ok := locals[rel]; !ok {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+
+	push := opts.Direction != SYNC_DIRECTION_PULL
+	pull := opts.Direction != SYNC_DIRECTION_PUSH
+	deleteExtraneous := opts.DeleteExtraneous && opts.Direction != SYNC_DIRECTION_BOTH
+
+	plan = &SyncPlan{LocalDir: localDir, RemoteDir: remoteDir}
+	var mkdirs, transfers, deletions []SyncAction
+	deletedDirs := make(map[string]bool)
+
+	// a first push creates the remote folder, a pull needs it
+	if !remoteExists {
+		if !push {
+			checkErr(&os.PathError{Op: "sync", Path: remoteDir, Err: os.ErrNotExist})
+		}
+		missing, err := c.missingDirs(remoteDir)
+		checkErr(err)
+		for _, dir := range missing {
+			mkdirs = append(mkdirs, SyncAction{
+				Op:     SYNC_OP_MKDIR_REMOTE,
+				Remote: dir,
+				Reason: "missing remotely",
+			})
+		}
+		if len(mkdirs) > 0 {
+			mkdirs[len(mkdirs)-1].Local = localDir
+		}
+	}
+
+	// a first pull creates the local folder, a push needs it
+	if !localExists {
+		if !pull {
+			checkErr(&os.PathError{Op: "sync", Path: localDir, Err: os.ErrNotExist})
+		}
+		mkdirs = append(mkdirs, SyncAction{
+			Op:     SYNC_OP_MKDIR_LOCAL,
+			Local:  localDir,
+			Remote: remoteDir,
+			Reason: "missing locally",
+		})
+	}
+
+	for _, rel := range rels {
+		local, remote := locals[rel], remotes[rel]
+		action := SyncAction{
+			Local:  filepath.Join(localDir, filepath.FromSlash(rel)),
+			Remote: path.Join(remoteDir, rel),
+		}
+
+		// everything below a deleted folder goes with it
+		if underDeleted(rel, deletedDirs) {
+			continue
+		}
+
+		switch {
+		case local != nil && remote != nil:
+			if local.isDir != remote.isDir {
+				action.Op, action.Reason = SYNC_OP_CONFLICT, "file and folder"
+				transfers = append(transfers, action)
+				continue
+			}
+			if local.isDir {
+				continue
+			}
+
+			changed, localNewer, reason, err := c.changed(ctx, action.Local, action.Remote, local, remote, opts)
+			checkErr(err)
+			if !changed {
+				continue
+			}
+
+			action.Reason = reason
+			if push && (localNewer || !pull) {
+				action.Op, action.Size = SYNC_OP_UPLOAD, local.size
+			} else {
+				action.Op, action.Size = SYNC_OP_DOWNLOAD, remote.size
+			}
+			transfers = append(transfers, action)
+
+		case local != nil:
+			switch {
+			case push && local.isDir:
+				action.Op, action.Reason = SYNC_OP_MKDIR_REMOTE, "missing remotely"
+				mkdirs = append(mkdirs, action)
+			case push:
+				action.Op, action.Size, action.Reason = SYNC_OP_UPLOAD, local.size, "missing remotely"
+				transfers = append(transfers, action)
+			case deleteExtraneous:
+				action.Op, action.Reason = SYNC_OP_DELETE_LOCAL, "missing remotely"
+				deletions = append(deletions, action)
+				if local.isDir {
+					deletedDirs[rel] = true
+				}
+			}
+
+		case remote != nil:
+			switch {
+			case pull && remote.isDir:
+				action.Op, action.Reason = SYNC_OP_MKDIR_LOCAL, "missing locally"
+				mkdirs = append(mkdirs, action)
+			case pull:
+				action.Op, action.Size, action.Reason = SYNC_OP_DOWNLOAD, remote.size, "missing locally"
+				transfers = append(transfers, action)
+			case deleteExtraneous:
+				action.Op, action.Reason = SYNC_OP_DELETE_REMOTE, "missing locally"
+				deletions = append(deletions, action)
+				if remote.isDir {
+					deletedDirs[rel] = true
+				}
+			}
+		}
+	}
+
+	plan.Actions = append(plan.Actions, mkdirs...)
+	plan.Actions = append(plan.Actions, transfers...)
+	plan.Actions = append(plan.Actions, deletions...)
+	return
+}
+
+func (c *Client) applyDownload(ctx context.Context, action *SyncAction) (err error) {
+	defer panicAttack(&err)
+
+	tmp := action.Local + syncTmpSuffix
+	err = c.DownloadToFile(ctx, action.Remote, tmp, nil)
+	checkErr(err)
+
+	err = os.Rename(tmp, action.Local)
+	checkErr(err)
+
+	// keep the remote date so the next run sees both sides as equal
+	info, err := c.Info(action.Remote)
+	checkErr(err)
+	modTime := info.Modification.Time
+	err = os.Chtimes(action.Local, modTime, modTime)
+	checkErr(err)
+	return
+}
+
+// ApplySync runs the actions of plan: folders are created first, then files
+// are transferred and finally extraneous files are deleted. The error of each
+// action is stored in it, the returned error tells how many failed.
+func (c *Client) ApplySync(ctx context.Context, plan *SyncPlan, opts *SyncOptions) (err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_UPLOAD_CONCURRENCY
+	}
+
+	var transfers []int
+	var remoteDeletions []string
+	for i := range plan.Actions {
+		action := &plan.Actions[i]
+		switch action.Op {
+		case SYNC_OP_MKDIR_REMOTE:
+			action.Err = c.ensureDir(action.Remote)
+		case SYNC_OP_MKDIR_LOCAL:
+			action.Err = os.MkdirAll(action.Local, 0755)
+		case SYNC_OP_UPLOAD, SYNC_OP_DOWNLOAD:
+			transfers = append(transfers, i)
+		case SYNC_OP_DELETE_LOCAL:
+			action.Err = os.RemoveAll(action.Local)
+		case SYNC_OP_DELETE_REMOTE:
+			remoteDeletions = append(remoteDeletions, action.Remote)
+		}
+	}
+
+	jobCh := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var session *UploadSession
+			defer func() {
+				if session != nil {
+					session.Close()
+				}
+			}()
+
+			for idx := range jobCh {
+				action := &plan.Actions[idx]
+				if action.Op == SYNC_OP_DOWNLOAD {
+					action.Err = c.applyDownload(ctx, action)
+					continue
+				}
+
+				if session == nil {
+					session, action.Err = c.NewUploadSession()
+					if action.Err != nil {
+						continue
+					}
+				}
+				uploadOpts := &UploadOptions{Conflict: UPLOAD_CONFLICT_OVERWRITE}
+				action.Err = session.UploadFile(ctx, action.Local, path.Dir(action.Remote), uploadOpts)
+			}
+		}()
+	}
+
+	for _, idx := range transfers {
+		jobCh <- idx
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if len(remoteDeletions) > 0 {
+		task, rmErr := c.Remove(remoteDeletions...)
+		if rmErr == nil {
+			task, rmErr = c.WaitTask(ctx, task.ID)
+		}
+		if rmErr == nil && !task.Succeeded() {
+			rmErr = fmt.Errorf("remove task failed: %s", task.Error)
+		}
+		for i := range plan.Actions {
+			if plan.Actions[i].Op == SYNC_OP_DELETE_REMOTE {
+				plan.Actions[i].Err = rmErr
+			}
+		}
+	}
+
+	failed := 0
+	for _, action := range plan.Actions {
+		if action.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		checkErr(fmt.Errorf("%d of %d sync actions failed", failed, len(plan.Actions)))
+	}
+	return
+}
+
+// Sync plans the synchronization of localDir and remoteDir then applies it
+// unless opts.DryRun is set. The plan is returned in both cases.
+func (c *Client) Sync(ctx context.Context, localDir, remoteDir string, opts *SyncOptions) (plan *SyncPlan, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+
+	plan, err = c.PlanSync(ctx, localDir, remoteDir, opts)
+	checkErr(err)
+
+	if !opts.DryRun {
+		err = c.ApplySync(ctx, plan, opts)
+		checkErr(err)
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package fbxapi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	opts := &SyncOptions{
+		Direction: SYNC_DIRECTION_PUSH,
+	}
+	_, err := testClient.Sync(context.Background(), "fixtures", "/Disque dur/fixtures", opts)
+	failOnError(t, err)
+
+	opts.DryRun = true
+	plan, err := testClient.Sync(context.Background(), "fixtures", "/Disque dur/fixtures", opts)
+	failOnError(t, err)
+
+	if len(plan.Actions) != 0 {
+		t.Fatalf("unexpected actions after sync:\n%s", plan)
+	}
+}
+
+func TestSyncChangedBoth(t *testing.T) {
+	opts := &SyncOptions{Direction: SYNC_DIRECTION_BOTH}
+	now := time.Now().Truncate(time.Second)
+	older := &syncEntry{size: 10, modTime: now.Add(-time.Hour)}
+	newer := &syncEntry{size: 10, modTime: now}
+
+	changed, localNewer, _, err := new(Client).changed(context.Background(), "", "", newer, older, opts)
+	failOnError(t, err)
+	if !changed || !localNewer {
+		t.Fatal("more recent local file of the same size not reported")
+	}
+
+	// the remote date of a pushed file is the upload date
+	changed, _, _, err = new(Client).changed(context.Background(), "", "", older, newer, opts)
+	failOnError(t, err)
+	if changed {
+		t.Fatal("file pushed by the previous run reported as changed")
+	}
+
+	changed, _, _, err = new(Client).changed(context.Background(), "", "", newer, newer, opts)
+	failOnError(t, err)
+	if changed {
+		t.Fatal("identical files reported as changed")
+	}
+}
+
+func TestUnderDeleted(t *testing.T) {
+	deleted := map[string]bool{"a": true}
+	for rel, want := range map[string]bool{
+		"a/x":     true,
+		"a/b/c/x": true,
+		"a-b/x":   false,
+		"a":       false,
+		"ab":      false,
+	} {
+		if got := underDeleted(rel, deleted); got != want {
+			t.Errorf("%s: expected %v, got %v", rel, want, got)
+		}
+	}
+}
+
+func TestSyncMissingLocalDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fbxsync")
+	failOnError(t, err)
+	defer os.RemoveAll(dir)
+
+	entries, exists, err := new(Client).localTree(filepath.Join(dir, "missing"), &SyncOptions{})
+	failOnError(t, err)
+	if exists || len(entries) != 0 {
+		t.Fatalf("missing folder listed: %v %v", exists, entries)
+	}
+}
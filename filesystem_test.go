@@ -1,10 +1,13 @@
 package fbxapi
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha1"
 	"io"
 	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -69,8 +72,147 @@ func TestFSDownload(t *testing.T) {
 func TestShare(t *testing.T) {
 	var data ShareLink
 	req := &ShareLink{
-		Path:   EncodePath("/Disque dur/lipsum.txt"),
-		Expire: int(time.Now().Add(time.Minute).Unix()),
+		Path:   FilePath("/Disque dur/lipsum.txt"),
+		Expire: NewTimestamp(time.Now().Add(time.Minute)),
 	}
 	EndpointTester(t, ShareEP, &data, nil, req)
 }
+
+func TestRepair(t *testing.T) {
+	// there is no par2 fixture, the repair of a plain file has to fail
+	task, err := testClient.Repair("/Disque dur/lipsum.txt")
+	failOnError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	task, err = testClient.WaitTask(ctx, task.ID)
+	failOnError(t, err)
+
+	if task.Type != FS_TASK_TYPE_REPAIR {
+		t.Fatalf("unexpected task type %s", task.Type)
+	}
+	if task.Err() == nil {
+		t.Fatal("repair of a plain file succeeded")
+	}
+}
+
+func TestFSTaskErr(t *testing.T) {
+	tests := []struct {
+		task FSTask
+		err  bool
+	}{
+		{FSTask{State: FS_TASK_STATE_RUNNING}, false},
+		{FSTask{State: FS_TASK_STATE_DONE, Error: FS_TASK_ERROR_NONE}, false},
+		{FSTask{State: FS_TASK_STATE_FAILED}, true},
+		{FSTask{State: FS_TASK_STATE_DONE, Error: "file_not_found"}, true},
+	}
+	for _, test := range tests {
+		if err := test.task.Err(); (err != nil) != test.err {
+			t.Errorf("%+v: unexpected error %v", test.task, err)
+		}
+	}
+
+	task := FSTask{Type: FS_TASK_TYPE_REPAIR, State: FS_TASK_STATE_FAILED, Error: FS_TASK_ERROR_REPAIR_FAILED}
+	if task.Err() != ErrRepairFailed {
+		t.Fatalf("unexpected error %v", task.Err())
+	}
+}
+
+func TestUploadDir(t *testing.T) {
+	opts := &UploadDirOptions{
+		Include: []string{"*.txt"},
+	}
+	results, err := testClient.UploadDir("fixtures", "/Disque dur/fixtures", opts)
+	failOnError(t, err)
+
+	if len(results) == 0 {
+		t.Fail()
+	}
+	for _, result := range results {
+		failOnError(t, result.Err)
+	}
+}
+
+func TestUploadReader(t *testing.T) {
+	content := []byte("Lorem ipsum dolor sit amet")
+	var uploaded int64
+
+	opts := &UploadOptions{
+		Progress: func(done, total int64) {
+			atomic.StoreInt64(&uploaded, done)
+		},
+	}
+	err := testClient.UploadReader(context.Background(), bytes.NewReader(content), int64(len(content)), "/Disque dur/", "lorem.txt", opts)
+	failOnError(t, err)
+
+	if done := atomic.LoadInt64(&uploaded); done != int64(len(content)) {
+		t.Fatalf("last progress %d of %d", done, len(content))
+	}
+}
+
+func TestUploads(t *testing.T) {
+	var data []FileUpload
+	EndpointTester(t, UploadsEP, &data, nil, nil)
+}
+
+func TestUploadSkip(t *testing.T) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	f, err := os.Open("fixtures/lipsum.txt")
+	failOnError(t, err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	failOnError(t, err)
+
+	opts := &UploadOptions{Conflict: UPLOAD_CONFLICT_SKIP}
+	err = testClient.UploadReader(context.Background(), f, fi.Size(), "/Disque dur/", fi.Name(), opts)
+	if err != ErrUploadSkipped {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadSession(t *testing.T) {
+	session, err := testClient.NewUploadSession()
+	failOnError(t, err)
+	defer session.Close()
+
+	errCh := make(chan error, 2)
+	for _, name := range []string{"lorem1.txt", "lorem2.txt"} {
+		go func(name string) {
+			content := []byte("Lorem ipsum dolor sit amet")
+			errCh <- session.Upload(context.Background(), bytes.NewReader(content), int64(len(content)), "/Disque dur/", name, nil)
+		}(name)
+	}
+
+	failOnError(t, <-errCh)
+	failOnError(t, <-errCh)
+}
+
+func TestMoveRename(t *testing.T) {
+	err := testClient.Upload("fixtures/lipsum.txt", "/Disque dur/")
+	failOnError(t, err)
+
+	_, err = testClient.Mkdir("/Disque dur/", "fbxapi-move")
+	failOnError(t, err)
+
+	task, err := testClient.Move([]string{"/Disque dur/lipsum.txt"}, "/Disque dur/fbxapi-move", FS_CONFLICT_OVERWRITE)
+	failOnError(t, err)
+	task, err = testClient.WaitTask(context.Background(), task.ID)
+	failOnError(t, err)
+	if !task.Succeeded() {
+		t.Fatal(task.Error)
+	}
+
+	info, err := testClient.Rename("/Disque dur/fbxapi-move/lipsum.txt", "renamed.txt")
+	failOnError(t, err)
+	if info.Name != "renamed.txt" {
+		t.Fail()
+	}
+
+	task, err = testClient.Remove("/Disque dur/fbxapi-move")
+	failOnError(t, err)
+	_, err = testClient.WaitTask(context.Background(), task.ID)
+	failOnError(t, err)
+}
@@ -0,0 +1,349 @@
+package fbxapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// FileType tells folders from regular files in FileInfo.
+type FileType string
+
+const FILE_TYPE_DIR FileType = "dir"
+const FILE_TYPE_FILE FileType = "file"
+
+func (t FileType) String() string {
+	return string(t)
+}
+
+func (t FileType) Known() bool {
+	return t == FILE_TYPE_DIR || t == FILE_TYPE_FILE
+}
+
+const DEFAULT_UPLOAD_CONCURRENCY = 2
+
+const UPLOAD_CHUNK_SIZE = 512000
+
+// Conflict policies, applied when the destination file already exists.
+const UPLOAD_CONFLICT_OVERWRITE = "overwrite"
+const UPLOAD_CONFLICT_RESUME = "resume"
+const UPLOAD_CONFLICT_SKIP = "skip"
+const UPLOAD_CONFLICT_RENAME = "auto"
+
+// ErrUploadSkipped is returned when the destination exists and the conflict
+// policy is UPLOAD_CONFLICT_SKIP.
+var ErrUploadSkipped = errors.New("upload skipped, destination already exists")
+
+// UploadsEP endpoint definition
+// Output: []FileUpload
+var UploadsEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "upload/",
+}
+
+// UploadEP endpoint definition
+// Output: FileUpload
+var UploadEP = &Endpoint{
+	Verb: HTTP_METHOD_GET,
+	Url:  "upload/{{.id}}",
+}
+
+// CancelUploadEP endpoint definition
+// Output: nil
+var CancelUploadEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "upload/{{.id}}/cancel",
+}
+
+// DeleteUploadEP endpoint definition
+// Output: nil
+var DeleteUploadEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "upload/{{.id}}",
+}
+
+// CleanUploadsEP endpoint definition
+// Output: nil
+var CleanUploadsEP = &Endpoint{
+	Verb: HTTP_METHOD_DELETE,
+	Url:  "upload/clean",
+}
+
+// UploadProgressFunc is called each time the box acknowledges received data,
+// uploaded being the amount of bytes it has written so far. It runs on the
+// goroutine calling Upload, which reports the full size before returning
+// successfully.
+type UploadProgressFunc func(uploaded, total int64)
+
+type UploadOptions struct {
+	Progress UploadProgressFunc
+	// Conflict is one of the UPLOAD_CONFLICT_* policies, defaults to
+	// UPLOAD_CONFLICT_OVERWRITE.
+	Conflict string
+	// ChunkSize is the size of the websocket frames, defaults to
+	// UPLOAD_CHUNK_SIZE.
+	ChunkSize int
+	// Window is the amount of bytes sent ahead of the box acknowledgments,
+	// defaults to DEFAULT_UPLOAD_WINDOW.
+	Window int64
+	// Stats is called along with Progress with the transfer metrics.
+	Stats UploadStatsFunc
+	// RateLimit throttles this upload, on top of the client upload limit.
+	RateLimit *RateLimiter
+	// KeepPartial leaves the partial file on the box when the upload fails
+	// or is cancelled, so a later UPLOAD_CONFLICT_RESUME continues it. By
+	// default the box is told to drop it.
+	KeepPartial bool
+}
+
+type UploadDirOptions struct {
+	// Concurrency is the number of files uploaded at the same time, each
+	// worker using its own websocket. Defaults to DEFAULT_UPLOAD_CONCURRENCY.
+	Concurrency int
+	// Include, when not empty, restricts the upload to the files matching at
+	// least one of the globs.
+	Include []string
+	// Exclude lists globs of files and folders to leave out.
+	Exclude []string
+	// Conflict is the UPLOAD_CONFLICT_* policy applied to every file.
+	Conflict string
+	// RateLimit throttles the whole folder upload, all workers sharing it.
+	RateLimit *RateLimiter
+}
+
+type UploadResult struct {
+	LocalPath  string
+	RemotePath string
+	Size       int64
+	Err        error
+}
+
+// matchAny checks the globs against both the slash separated relative path
+// and the base name, so "*.jpg" and "2017/*.jpg" both behave as expected.
+func matchAny(globs []string, relPath string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(glob, path.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureDir creates the remote folder dir unless it already exists.
+func (c *Client) ensureDir(dir string) (err error) {
+	defer panicAttack(&err)
+
+	if info, infoErr := c.Info(dir); infoErr == nil {
+		if info.Type != FILE_TYPE_DIR {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+		}
+		return
+	}
+
+	parent, name := path.Split(path.Clean(dir))
+	_, err = c.Mkdir(parent, name)
+	checkErr(err)
+	return
+}
+
+// UploadDir mirrors the tree found under localDir inside remoteDir, creating
+// the remote folders as needed. A result is returned for every file which was
+// not filtered out, err is only set when the tree could not be mirrored.
+func (c *Client) UploadDir(localDir, remoteDir string, opts *UploadDirOptions) (results []UploadResult, err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &UploadDirOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_UPLOAD_CONCURRENCY
+	}
+
+	err = c.ensureDir(remoteDir)
+	checkErr(err)
+
+	var jobs []UploadResult
+	err = filepath.Walk(localDir, func(localPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, localPath)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(opts.Exclude, rel) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		remotePath := path.Join(remoteDir, rel)
+		if fi.IsDir() {
+			return c.ensureDir(remotePath)
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+
+		jobs = append(jobs, UploadResult{
+			LocalPath:  localPath,
+			RemotePath: remotePath,
+			Size:       fi.Size(),
+		})
+		return nil
+	})
+	checkErr(err)
+
+	fileOpts := &UploadOptions{Conflict: opts.Conflict, RateLimit: opts.RateLimit}
+	results = make([]UploadResult, len(jobs))
+	jobCh := make(chan int)
+	var wg sync.WaitGroup
+
+	// every worker keeps its own websocket for all the files it uploads
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session, sessionErr := c.NewUploadSession()
+			if sessionErr == nil {
+				defer session.Close()
+			}
+
+			for idx := range jobCh {
+				job := jobs[idx]
+				if sessionErr != nil {
+					job.Err = sessionErr
+				} else {
+					job.Err = session.UploadFile(context.Background(), job.LocalPath, path.Dir(job.RemotePath), fileOpts)
+				}
+				results[idx] = job
+			}
+		}()
+	}
+
+	for idx := range jobs {
+		jobCh <- idx
+	}
+	close(jobCh)
+	wg.Wait()
+
+	return
+}
+
+// UploadReader uploads size bytes read from r as destDir/name on a dedicated
+// websocket. Cancelling ctx aborts the transfer.
+func (c *Client) UploadReader(ctx context.Context, r io.Reader, size int64, destDir, name string, opts *UploadOptions) (err error) {
+	defer panicAttack(&err)
+
+	session, err := c.NewUploadSession()
+	checkErr(err)
+	defer session.Close()
+
+	err = session.Upload(ctx, r, size, destDir, name, opts)
+	checkErr(err)
+	return
+}
+
+// UploadFile uploads the local file at path inside destDir.
+func (s *UploadSession) UploadFile(ctx context.Context, path, destDir string, opts *UploadOptions) (err error) {
+	defer panicAttack(&err)
+
+	f, err := os.Open(path)
+	checkErr(err)
+	defer f.Close()
+
+	fi, err := f.Stat()
+	checkErr(err)
+
+	err = s.Upload(ctx, f, fi.Size(), destDir, fi.Name(), opts)
+	checkErr(err)
+	return
+}
+
+func (c *Client) Upload(path, destDir string) (err error) {
+	defer panicAttack(&err)
+
+	session, err := c.NewUploadSession()
+	checkErr(err)
+	defer session.Close()
+
+	err = session.UploadFile(context.Background(), path, destDir, nil)
+	checkErr(err)
+	return
+}
+
+func (c *Client) Uploads() (uploads []FileUpload, err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(UploadsEP).Do(&uploads)
+	checkErr(err)
+	return
+}
+
+func (c *Client) UploadInfo(id int) (upload *FileUpload, err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	upload = new(FileUpload)
+	err = c.Query(UploadEP).As(params).Do(upload)
+	checkErr(err)
+	return
+}
+
+func (c *Client) CancelUpload(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(CancelUploadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// DeleteUpload removes the upload from the history, the uploaded file is kept.
+func (c *Client) DeleteUpload(id int) (err error) {
+	defer panicAttack(&err)
+
+	params := map[string]string{
+		"id": strconv.Itoa(id),
+	}
+
+	err = c.Query(DeleteUploadEP).As(params).Do(nil)
+	checkErr(err)
+	return
+}
+
+// CleanUploads removes every finished upload from the history.
+func (c *Client) CleanUploads() (err error) {
+	defer panicAttack(&err)
+
+	err = c.Query(CleanUploadsEP).Do(nil)
+	checkErr(err)
+	return
+}
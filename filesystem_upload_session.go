@@ -0,0 +1,381 @@
+package fbxapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// UPLOAD_CANCEL_GRACE is how long a cancelled upload waits for its reader to
+// return before closing the session.
+const UPLOAD_CANCEL_GRACE = 100 * time.Millisecond
+
+var ErrUploadSessionClosed = errors.New("upload session closed")
+
+// ErrUploadReaderBlocked fails the uploads of a session closed because the
+// reader of a cancelled upload did not return.
+var ErrUploadReaderBlocked = errors.New("upload session closed, reader blocked after cancellation")
+
+// uploadReqID is shared by all the sessions so request ids stay unique for
+// the whole process, it is seeded from the clock to avoid reusing the ids of
+// a previous run.
+var uploadReqID = time.Now().Unix()
+
+func nextUploadReqID() int {
+	return int(atomic.AddInt64(&uploadReqID, 1) & 0x7fffffff)
+}
+
+// uploadStream receives the responses of the upload using the websocket.
+type uploadStream struct {
+	reqID  int
+	respCh chan *WSResponse
+	done   chan struct{}
+}
+
+// UploadSession keeps a ws/upload websocket open so several files can be
+// uploaded one after the other without reconnecting.
+//
+// The protocol cannot multiplex uploads: data frames carry no request id and
+// the box attributes them to the upload in progress. Concurrent calls to
+// Upload wait for each other, use several sessions to upload in parallel.
+type UploadSession struct {
+	client *Client
+	conn   *websocket.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+	// stream is the upload using the websocket, if any
+	stream *uploadStream
+	err    error
+	// transfer is held by the upload using the websocket
+	transfer sync.Mutex
+	// reading is set while the sender waits on the reader of the upload
+	reading int32
+}
+
+func (c *Client) NewUploadSession() (session *UploadSession, err error) {
+	defer panicAttack(&err)
+
+	conn, err := c.Query(UlEP).WS()
+	checkErr(err)
+
+	session = newUploadSession(c, conn)
+	return
+}
+
+func newUploadSession(c *Client, conn *websocket.Conn) *UploadSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &UploadSession{
+		client: c,
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	go session.receive()
+	return session
+}
+
+func (s *UploadSession) Close() error {
+	s.fail(ErrUploadSessionClosed)
+	return s.conn.Close()
+}
+
+// fail stops the session, err being reported to the pending uploads.
+func (s *UploadSession) fail(err error) {
+	s.mutex.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mutex.Unlock()
+	s.cancel()
+}
+
+func (s *UploadSession) sessionErr() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+func (s *UploadSession) receive() {
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(s.conn, &message); err != nil {
+			s.fail(err)
+			return
+		}
+
+		resp := new(WSResponse)
+		if err := json.Unmarshal(message, resp); err != nil {
+			continue
+		}
+
+		// late responses of a previous upload are dropped
+		s.mutex.Lock()
+		stream := s.stream
+		s.mutex.Unlock()
+
+		if stream != nil && stream.reqID == resp.RequestID {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-stream.done:
+			case stream.respCh <- resp:
+			}
+		}
+	}
+}
+
+// open makes reqID the upload receiving the responses.
+func (s *UploadSession) open(reqID int) *uploadStream {
+	stream := &uploadStream{
+		reqID:  reqID,
+		respCh: make(chan *WSResponse),
+		done:   make(chan struct{}),
+	}
+
+	s.mutex.Lock()
+	s.stream = stream
+	s.mutex.Unlock()
+
+	return stream
+}
+
+// end drops the responses still addressed to stream, including one the
+// receiver may be blocked on.
+func (s *UploadSession) end(stream *uploadStream) {
+	s.mutex.Lock()
+	if s.stream == stream {
+		s.stream = nil
+	}
+	s.mutex.Unlock()
+	close(stream.done)
+}
+
+// read fills buf from r, flagging the session as waiting on the reader. The
+// flag is set before ctx is checked so that an upload cancelled while it is
+// cleared knows the sender will not read again.
+func (s *UploadSession) read(ctx context.Context, r io.Reader, buf []byte) (int, error) {
+	atomic.StoreInt32(&s.reading, 1)
+	defer atomic.StoreInt32(&s.reading, 0)
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r, buf)
+}
+
+// sendData streams r on the websocket, never letting more than the window
+// size wait for an acknowledgment.
+func (s *UploadSession) sendData(ctx context.Context, r io.Reader, reqID int, window *uploadWindow, chunkSize int) (err error) {
+	defer panicAttack(&err)
+
+	buf := make([]byte, chunkSize)
+
+	for {
+		n, err := s.read(ctx, r, buf)
+		if n > 0 {
+			if !window.reserve(int64(n)) {
+				return ctx.Err()
+			}
+			sendErr := websocket.Message.Send(s.conn, buf[:n])
+			checkErr(sendErr)
+			window.markSent(int64(n))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		checkErr(err)
+	}
+
+	reqUploadFinalize := &WSRequest{
+		Action:    "upload_finalize",
+		RequestID: reqID,
+	}
+
+	err = websocket.JSON.Send(s.conn, reqUploadFinalize)
+	checkErr(err)
+	return
+}
+
+// Upload uploads size bytes read from r as destDir/name. Cancelling ctx
+// aborts the transfer and returns at once. The session stays usable, unless r
+// was blocked in Read: the websocket is then closed and the next uploads fail
+// with ErrUploadReaderBlocked.
+func (s *UploadSession) Upload(ctx context.Context, r io.Reader, size int64, destDir, name string, opts *UploadOptions) (err error) {
+	defer panicAttack(&err)
+
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	s.transfer.Lock()
+	defer s.transfer.Unlock()
+
+	err = s.sessionErr()
+	checkErr(err)
+
+	reqID := nextUploadReqID()
+	stream := s.open(reqID)
+	defer s.end(stream)
+	respCh := stream.respCh
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// ctxErr prefers the session failure over the bare cancellation it
+	// triggered.
+	ctxErr := func() error {
+		if err := s.sessionErr(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+
+	force := opts.Conflict
+	switch force {
+	case "":
+		force = UPLOAD_CONFLICT_OVERWRITE
+	case UPLOAD_CONFLICT_SKIP:
+		// the box refuses to touch an existing file when force is left out
+		force = ""
+	}
+
+	reqUploadStart := &FileUploadStartAction{
+		WSRequest: WSRequest{
+			Action:    "upload_start",
+			RequestID: reqID,
+		},
+		Size:     int(size),
+		Dirname:  FilePath(destDir),
+		Filename: name,
+		Force:    force,
+	}
+
+	err = websocket.JSON.Send(s.conn, reqUploadStart)
+	checkErr(err)
+
+	var resp *WSResponse
+	select {
+	case <-ctx.Done():
+		checkErr(ctxErr())
+	case resp = <-respCh:
+	}
+	if !resp.Success {
+		if opts.Conflict == UPLOAD_CONFLICT_SKIP && resp.ErrorCode == UPLOAD_STATUS_CONFLICT {
+			return ErrUploadSkipped
+		}
+		return errors.New(resp.Msg)
+	}
+
+	var offset int64
+	if force == UPLOAD_CONFLICT_RESUME {
+		// the box tells how much of the file it already has
+		chunk := new(FileUploadChunkResult)
+		if json.Unmarshal(resp.Result, chunk) == nil && chunk.TotalLen > 0 {
+			offset = int64(chunk.TotalLen)
+			_, err = io.CopyN(ioutil.Discard, r, offset)
+			checkErr(err)
+		}
+	}
+
+	// any exit before the box confirmed the upload, including a cancelled
+	// ctx, tells it to drop the partial file unless it is to be resumed
+	finalized := false
+	defer func() {
+		if !finalized && !opts.KeepPartial {
+			cancelReq := &WSRequest{
+				Action:    "upload_cancel",
+				RequestID: reqID,
+			}
+			websocket.JSON.Send(s.conn, cancelReq)
+		}
+	}()
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = UPLOAD_CHUNK_SIZE
+	}
+	window := newUploadWindow(opts.Window, offset)
+	go window.watch(ctx)
+
+	_, uploadLimit := s.client.limits()
+	r = newLimitedReader(ctx, r, uploadLimit, opts.RateLimit)
+
+	// the sender must be gone before the next upload may use the websocket,
+	// one stuck in a Read ignoring ctx would hold it forever so the session
+	// is given up instead
+	errorCh := make(chan error, 1)
+	senderDone := make(chan struct{})
+	defer func() {
+		cancel()
+		select {
+		case <-senderDone:
+			return
+		case <-time.After(UPLOAD_CANCEL_GRACE):
+		}
+		if atomic.LoadInt32(&s.reading) == 1 {
+			s.fail(ErrUploadReaderBlocked)
+			s.conn.Close()
+			return
+		}
+		<-senderDone
+	}()
+	go func() {
+		defer close(senderDone)
+		errorCh <- s.sendData(ctx, r, reqID, window, chunkSize)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			checkErr(ctxErr())
+		case err = <-errorCh:
+			checkErr(err)
+		case resp = <-respCh:
+			switch resp.Action {
+			case "upload_data":
+				if !resp.Success {
+					checkErr(errors.New(resp.Msg))
+				}
+
+				chunk := new(FileUploadChunkResult)
+				json.Unmarshal(resp.Result, chunk)
+				window.ack(int64(chunk.TotalLen))
+
+				stats := window.stats()
+				if opts.Progress != nil {
+					opts.Progress(stats.Acked, size)
+				}
+				if opts.Stats != nil {
+					opts.Stats(stats)
+				}
+			case "upload_finalize":
+				finalized = true
+				if !resp.Success {
+					return errors.New(resp.Msg)
+				}
+				// the last acknowledgment may come after the finalization
+				if opts.Progress != nil && window.stats().Acked < size {
+					opts.Progress(size, size)
+				}
+				return
+			}
+		}
+	}
+}
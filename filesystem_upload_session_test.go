@@ -0,0 +1,80 @@
+package fbxapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// blockedReader returns a few bytes then blocks forever, ignoring any
+// cancellation.
+type blockedReader struct {
+	read bool
+}
+
+func (b *blockedReader) Read(p []byte) (int, error) {
+	if !b.read {
+		b.read = true
+		return copy(p, "abc"), nil
+	}
+	select {}
+}
+
+// testUploadSession opens a session on a fake ws/upload accepting every
+// upload_start and never acknowledging the data.
+func testUploadSession(t *testing.T) *UploadSession {
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		for {
+			var req WSRequest
+			if err := websocket.JSON.Receive(ws, &req); err != nil {
+				if _, ok := err.(*json.SyntaxError); ok {
+					continue
+				}
+				return
+			}
+			if req.Action == "upload_start" {
+				websocket.JSON.Send(ws, &WSResponse{Action: req.Action, RequestID: req.RequestID, Success: true})
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", "http://localhost")
+	failOnError(t, err)
+	session := newUploadSession(new(Client), conn)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestUploadSessionCancel(t *testing.T) {
+	opts := &UploadOptions{ChunkSize: 10}
+
+	session := testUploadSession(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := session.Upload(ctx, strings.NewReader(strings.Repeat("x", 100)), 1000, "/Disque dur", "a.bin", opts)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if err = session.sessionErr(); err != nil {
+		t.Fatalf("session closed by a cancelled upload: %v", err)
+	}
+
+	session = testUploadSession(t)
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = session.Upload(ctx, &blockedReader{}, 1000, "/Disque dur", "b.bin", opts)
+	if err != context.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Fatalf("upload with a blocked reader returned %v after %v", err, time.Since(start))
+	}
+	err = session.Upload(context.Background(), strings.NewReader("c"), 1, "/Disque dur", "c.bin", nil)
+	if err != ErrUploadReaderBlocked {
+		t.Fatalf("expected %v, got %v", ErrUploadReaderBlocked, err)
+	}
+}
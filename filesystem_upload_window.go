@@ -0,0 +1,134 @@
+package fbxapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DEFAULT_UPLOAD_WINDOW is the default amount of bytes which may be sent
+// without having been acknowledged by the box.
+const DEFAULT_UPLOAD_WINDOW = 4 * UPLOAD_CHUNK_SIZE
+
+type UploadStats struct {
+	Sent  int64
+	Acked int64
+	// Throughput is the acknowledged bytes per second since the upload start.
+	Throughput float64
+	// RTT is a smoothed delay between sending a chunk and its acknowledgment.
+	RTT time.Duration
+}
+
+type UploadStatsFunc func(stats UploadStats)
+
+type sentMark struct {
+	end  int64
+	date time.Time
+}
+
+// uploadWindow bounds the amount of data in flight on an upload websocket,
+// the sender blocks in reserve until upload_data acknowledgments free room.
+type uploadWindow struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	size   int64
+	offset int64
+	sent   int64
+	acked  int64
+	marks  []sentMark
+	start  time.Time
+	rtt    time.Duration
+	closed bool
+}
+
+func newUploadWindow(size, offset int64) *uploadWindow {
+	if size <= 0 {
+		size = DEFAULT_UPLOAD_WINDOW
+	}
+	w := &uploadWindow{
+		size:   size,
+		offset: offset,
+		sent:   offset,
+		acked:  offset,
+		start:  time.Now(),
+	}
+	w.cond = sync.NewCond(&w.mutex)
+	return w
+}
+
+// watch closes the window once ctx is done so a blocked sender wakes up.
+func (w *uploadWindow) watch(ctx context.Context) {
+	<-ctx.Done()
+	w.close()
+}
+
+func (w *uploadWindow) close() {
+	w.mutex.Lock()
+	w.closed = true
+	w.mutex.Unlock()
+	w.cond.Broadcast()
+}
+
+// reserve waits until n more bytes fit in the window. It returns false when
+// the window was closed meanwhile. A single chunk larger than the window is
+// let through once nothing is in flight.
+func (w *uploadWindow) reserve(n int64) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for !w.closed && w.sent > w.acked && w.sent+n-w.acked > w.size {
+		w.cond.Wait()
+	}
+	return !w.closed
+}
+
+func (w *uploadWindow) markSent(n int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.sent += n
+	w.marks = append(w.marks, sentMark{end: w.sent, date: time.Now()})
+}
+
+// ack records an upload_data acknowledgment. totalLen is the amount of bytes
+// written by the box, when it is not provided the oldest chunk in flight is
+// considered acknowledged.
+func (w *uploadWindow) ack(totalLen int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if totalLen <= 0 && len(w.marks) > 0 {
+		totalLen = w.marks[0].end
+	}
+	if totalLen > w.acked {
+		w.acked = totalLen
+	}
+
+	now := time.Now()
+	for len(w.marks) > 0 && w.marks[0].end <= w.acked {
+		sample := now.Sub(w.marks[0].date)
+		if w.rtt == 0 {
+			w.rtt = sample
+		} else {
+			w.rtt = (7*w.rtt + sample) / 8
+		}
+		w.marks = w.marks[1:]
+	}
+
+	w.cond.Broadcast()
+}
+
+func (w *uploadWindow) stats() UploadStats {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	stats := UploadStats{
+		Sent:  w.sent,
+		Acked: w.acked,
+		RTT:   w.rtt,
+	}
+	if elapsed := time.Since(w.start).Seconds(); elapsed > 0 {
+		stats.Throughput = float64(w.acked-w.offset) / elapsed
+	}
+	return stats
+}
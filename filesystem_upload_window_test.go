@@ -0,0 +1,45 @@
+package fbxapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUploadWindow(t *testing.T) {
+	window := newUploadWindow(10, 0)
+
+	if !window.reserve(8) {
+		t.Fatal("window closed")
+	}
+	window.markSent(8)
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- window.reserve(8)
+	}()
+
+	select {
+	case <-reserved:
+		t.Fatal("reserve did not wait for the acknowledgment")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	window.ack(8)
+	if !<-reserved {
+		t.Fatal("window closed")
+	}
+
+	stats := window.stats()
+	if stats.Acked != 8 || stats.RTT == 0 {
+		t.Fatalf("unexpected stats %#v", stats)
+	}
+
+	window.markSent(8)
+	go func() {
+		reserved <- window.reserve(8)
+	}()
+	window.close()
+	if <-reserved {
+		t.Fatal("reserve succeeded on a closed window")
+	}
+}
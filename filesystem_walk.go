@@ -0,0 +1,214 @@
+package fbxapi
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+const DEFAULT_WALK_CONCURRENCY = 4
+
+// WalkFunc is called for every file and folder visited by Walk, with the same
+// semantics as fs.WalkDirFunc: returning fs.SkipDir skips the folder (or the
+// remaining entries of the parent folder for a file) and fs.SkipAll stops the
+// walk. err is set when info could not be retrieved or the folder could not
+// be listed, in that last case fn is called a second time for the folder.
+type WalkFunc func(path string, info *FileInfo, err error) error
+
+type WalkOptions struct {
+	// FollowSymlinks descends into the folders targeted by symbolic links,
+	// each folder being visited once to break loops.
+	FollowSymlinks bool
+	// IncludeHidden lists hidden files, they are left out by default.
+	IncludeHidden bool
+	// Concurrency bounds the number of entries ahead of fn whose folders
+	// are listed in advance, defaults to DEFAULT_WALK_CONCURRENCY.
+	Concurrency int
+}
+
+type listing struct {
+	infos  []FileInfo
+	err    error
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+type walker struct {
+	client  *Client
+	ctx     context.Context
+	opts    *WalkOptions
+	sem     chan struct{}
+	visited map[string]bool
+}
+
+// list fetches the entries of dir in the background, sorted by name, once a
+// slot is available.
+func (w *walker) list(dir string) *listing {
+	ctx, cancel := context.WithCancel(w.ctx)
+	l := &listing{done: make(chan struct{}), cancel: cancel}
+
+	go func() {
+		defer close(l.done)
+
+		select {
+		case <-ctx.Done():
+			l.err = ctx.Err()
+			return
+		case w.sem <- struct{}{}:
+		}
+		defer func() { <-w.sem }()
+
+		infos, err := w.client.lsContext(ctx, dir, false, false, !w.opts.IncludeHidden)
+		if err != nil {
+			l.err = err
+			return
+		}
+
+		l.infos = infos[:0]
+		for _, info := range infos {
+			if info.Name != "." && info.Name != ".." {
+				l.infos = append(l.infos, info)
+			}
+		}
+		sort.Slice(l.infos, func(i, j int) bool {
+			return l.infos[i].Name < l.infos[j].Name
+		})
+	}()
+
+	return l
+}
+
+// realDir returns the path on the box of the folder to descend into for info,
+// ok is false when it must not be walked.
+func (w *walker) realDir(parent string, info *FileInfo) (dir string, ok bool) {
+	if info.Type != FILE_TYPE_DIR {
+		return
+	}
+	if !info.Link {
+		return path.Join(parent, info.Name), true
+	}
+	if !w.opts.FollowSymlinks {
+		return
+	}
+	return path.Clean(string(info.Target)), true
+}
+
+// walk visits the folder p whose listing l was started earlier. real is the
+// folder path on the box, which differs from p behind a symbolic link.
+func (w *walker) walk(p, real string, info *FileInfo, l *listing, fn WalkFunc) error {
+	<-l.done
+	if l.err != nil {
+		err := fn(p, info, l.err)
+		if err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	// the sub folders are listed a few entries ahead of fn, the listings of
+	// the folders it skips or never reaches are cancelled
+	listings := make([]*listing, len(l.infos))
+	reals := make([]string, len(l.infos))
+	prefetched := 0
+	defer func() {
+		for _, l := range listings {
+			if l != nil {
+				l.cancel()
+			}
+		}
+	}()
+
+	for i := range l.infos {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+
+		for ; prefetched < len(l.infos) && prefetched < i+cap(w.sem); prefetched++ {
+			// a folder reached directly is always walked, one behind a
+			// link only if it was not walked yet
+			info := &l.infos[prefetched]
+			if dir, ok := w.realDir(real, info); ok && !(info.Link && w.visited[dir]) {
+				reals[prefetched] = dir
+				listings[prefetched] = w.list(dir)
+			}
+		}
+
+		child := &l.infos[i]
+		childPath := path.Join(p, child.Name)
+
+		err := fn(childPath, child, nil)
+		if err == fs.SkipDir {
+			if child.Type == FILE_TYPE_DIR {
+				if listings[i] != nil {
+					listings[i].cancel()
+				}
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if listings[i] != nil {
+			// the target of a link may have been walked since it was
+			// listed
+			if child.Link && w.visited[reals[i]] {
+				listings[i].cancel()
+				continue
+			}
+			w.visited[reals[i]] = true
+			if err := w.walk(childPath, reals[i], child, listings[i], fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for root and every file and folder below it, in lexical
+// order. Hidden files are skipped and symbolic links are not followed.
+func (c *Client) Walk(ctx context.Context, root string, fn WalkFunc) error {
+	return c.WalkWithOptions(ctx, root, nil, fn)
+}
+
+// WalkWithOptions is Walk with a custom hidden files, symbolic links and
+// concurrency policy. fn is never called concurrently.
+func (c *Client) WalkWithOptions(ctx context.Context, root string, opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_WALK_CONCURRENCY
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{
+		client:  c,
+		ctx:     ctx,
+		opts:    opts,
+		sem:     make(chan struct{}, concurrency),
+		visited: make(map[string]bool),
+	}
+
+	root = path.Clean(root)
+	info, err := c.Info(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = fn(root, info, nil)
+		if err == nil && info.Type == FILE_TYPE_DIR {
+			w.visited[root] = true
+			err = w.walk(root, root, info, w.list(root), fn)
+		}
+	}
+
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
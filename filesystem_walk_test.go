@@ -0,0 +1,35 @@
+package fbxapi
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	_, err := testClient.UploadDir("fixtures", "/Disque dur/fixtures", nil)
+	failOnError(t, err)
+
+	var paths []string
+	err = testClient.Walk(context.Background(), "/Disque dur/fixtures", func(path string, info *FileInfo, err error) error {
+		failOnError(t, err)
+		paths = append(paths, path)
+		return nil
+	})
+	failOnError(t, err)
+
+	if !isIn("/Disque dur/fixtures/lipsum.txt", paths) {
+		t.Fatal(paths)
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	err := testClient.Walk(context.Background(), "/", func(path string, info *FileInfo, err error) error {
+		failOnError(t, err)
+		if path != "/" && info.Type == FILE_TYPE_DIR {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	failOnError(t, err)
+}
@@ -0,0 +1,263 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/jsurloppe/fbxapi"
+)
+
+// ARCHIVE_EXTENSIONS are the extensions Extract looks for in a folder.
+var ARCHIVE_EXTENSIONS = []string{".rar", ".zip", ".7z", ".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tar.xz"}
+
+// Client is the part of *fbxapi.Client used by the engine and the actions.
+type Client interface {
+	ListDownloads() ([]fbxapi.Download, error)
+	DownloadTrackers(id int) ([]fbxapi.DownloadTracker, error)
+	EraseDownload(id int) error
+	Info(path string) (*fbxapi.FileInfo, error)
+	Ls(path string, onlyFolder, countSubFolder, removeHidden bool) ([]fbxapi.FileInfo, error)
+	Mkdir(parent, dirname string) (string, error)
+	Move(paths []string, dstDir, mode string) (*fbxapi.FSTask, error)
+	Rename(path, newName string) (*fbxapi.FileInfo, error)
+	Remove(paths ...string) (*fbxapi.FSTask, error)
+	Extract(src, dst string, opts *fbxapi.ExtractOptions) (*fbxapi.FSTask, error)
+	WaitTask(ctx context.Context, id int) (*fbxapi.FSTask, error)
+	CreateShareLinkFor(path string, d time.Duration) (*fbxapi.ShareLink, error)
+}
+
+// Job is the download being processed, actions update Path as they move the
+// result around.
+type Job struct {
+	Client   Client
+	Download *fbxapi.Download
+	// Path is the current location on the box of the download result.
+	Path      string
+	ShareLink *fbxapi.ShareLink
+	Log       logrus.FieldLogger
+}
+
+// Action is a step of a Rule.
+type Action interface {
+	Run(ctx context.Context, job *Job) error
+}
+
+// wait waits for the file system task and turns its failure into an error.
+func (job *Job) wait(ctx context.Context, task *fbxapi.FSTask) error {
+	task, err := job.Client.WaitTask(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if !task.Succeeded() {
+		return errors.New(task.Error)
+	}
+	return nil
+}
+
+// archiveExt returns the longest of ARCHIVE_EXTENSIONS ending name, empty
+// when name is not an archive.
+func archiveExt(name string) (ext string) {
+	name = strings.ToLower(name)
+	for _, candidate := range ARCHIVE_EXTENSIONS {
+		if strings.HasSuffix(name, candidate) && len(candidate) > len(ext) {
+			ext = candidate
+		}
+	}
+	return
+}
+
+func isArchive(name string) bool {
+	return archiveExt(name) != ""
+}
+
+var rarVolumeRe = regexp.MustCompile(`(?i)\.part(\d+)\.rar$`)
+var volumeSuffixRe = regexp.MustCompile(`(?i)\.part\d+$`)
+
+// archiveBase strips the archive extension and the volume number of name,
+// "movie.part01.rar" and "movie.tar.gz" both giving "movie".
+func archiveBase(name string) string {
+	name = name[:len(name)-len(archiveExt(name))]
+	return volumeSuffixRe.ReplaceAllString(name, "")
+}
+
+// isFirstVolume tells whether name is not a rar volume following the first.
+func isFirstVolume(name string) bool {
+	m := rarVolumeRe.FindStringSubmatch(name)
+	if m == nil {
+		return true
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n == 1
+}
+
+// Extract extracts the archive of the download. When the download is a
+// folder, the first archive found inside is extracted into it and Path is
+// left unchanged, otherwise the archive is extracted into a new folder
+// named after it which becomes the Path. That folder is reused when it
+// already exists.
+type Extract struct {
+	Password      string
+	DeleteArchive bool
+}
+
+func (a *Extract) Run(ctx context.Context, job *Job) error {
+	info, err := job.Client.Info(job.Path)
+	if err != nil {
+		return err
+	}
+
+	var archive, dst string
+	if info.Type == fbxapi.FILE_TYPE_DIR {
+		files, err := job.Client.Ls(job.Path, false, false, false)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if file.Type == fbxapi.FILE_TYPE_FILE && isArchive(file.Name) && isFirstVolume(file.Name) {
+				archive = path.Join(job.Path, file.Name)
+				break
+			}
+		}
+		if archive == "" {
+			job.Log.Info("no archive to extract")
+			return nil
+		}
+		dst = job.Path
+	} else {
+		if !isArchive(info.Name) {
+			job.Log.Info("not an archive, nothing to extract")
+			return nil
+		}
+		archive = job.Path
+		parent, name := path.Split(job.Path)
+		dst = path.Join(parent, archiveBase(name))
+		if existing, err := job.Client.Info(dst); err != nil || existing.Type != fbxapi.FILE_TYPE_DIR {
+			if dst, err = job.Client.Mkdir(parent, archiveBase(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	job.Log.WithField("archive", archive).Infof("extracting into %s", dst)
+	task, err := job.Client.Extract(archive, dst, &fbxapi.ExtractOptions{
+		Password:      a.Password,
+		DeleteArchive: a.DeleteArchive,
+	})
+	if err != nil {
+		return err
+	}
+	if err = job.wait(ctx, task); err != nil {
+		return err
+	}
+
+	job.Path = dst
+	return nil
+}
+
+// Move moves the result into Dir, replacing any file with the same name.
+type Move struct {
+	Dir string
+}
+
+func (a *Move) Run(ctx context.Context, job *Job) error {
+	job.Log.Infof("moving %s to %s", job.Path, a.Dir)
+	task, err := job.Client.Move([]string{job.Path}, a.Dir, fbxapi.FS_CONFLICT_OVERWRITE)
+	if err != nil {
+		return err
+	}
+	if err = job.wait(ctx, task); err != nil {
+		return err
+	}
+
+	job.Path = path.Join(a.Dir, path.Base(job.Path))
+	return nil
+}
+
+// Rename replaces the matches of Pattern in the result name by Replace,
+// which may refer to the submatches as in regexp.Regexp.ReplaceAllString.
+type Rename struct {
+	Pattern string
+	Replace string
+}
+
+func (a *Rename) Run(ctx context.Context, job *Job) error {
+	re, err := regexp.Compile(a.Pattern)
+	if err != nil {
+		return err
+	}
+
+	name := re.ReplaceAllString(path.Base(job.Path), a.Replace)
+	if name == path.Base(job.Path) {
+		return nil
+	}
+
+	job.Log.Infof("renaming %s to %s", job.Path, name)
+	info, err := job.Client.Rename(job.Path, name)
+	if err != nil {
+		return err
+	}
+
+	job.Path = string(info.Path)
+	return nil
+}
+
+// Remove deletes the files of the download folder matching Pattern, a
+// shell pattern such as "*.nfo". It is typically used to drop archives once
+// extracted.
+type Remove struct {
+	Pattern string
+}
+
+func (a *Remove) Run(ctx context.Context, job *Job) error {
+	files, err := job.Client.Ls(job.Path, false, false, false)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, file := range files {
+		if matched, _ := path.Match(a.Pattern, file.Name); matched && file.Name != "." && file.Name != ".." {
+			paths = append(paths, path.Join(job.Path, file.Name))
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	job.Log.Infof("removing %s", strings.Join(paths, ", "))
+	task, err := job.Client.Remove(paths...)
+	if err != nil {
+		return err
+	}
+	return job.wait(ctx, task)
+}
+
+// Erase erases the download task along with the files still in the
+// download folder, run it after moving the result out.
+type Erase struct{}
+
+func (a *Erase) Run(ctx context.Context, job *Job) error {
+	job.Log.Info("erasing the download task")
+	return job.Client.EraseDownload(job.Download.ID)
+}
+
+// Share creates a public link to the result, valid for Expire or forever
+// when zero.
+type Share struct {
+	Expire time.Duration
+}
+
+func (a *Share) Run(ctx context.Context, job *Job) (err error) {
+	job.ShareLink, err = job.Client.CreateShareLinkFor(job.Path, a.Expire)
+	if err != nil {
+		return err
+	}
+	job.Log.Infof("shared %s at %s", job.Path, job.ShareLink.FullURL)
+	return nil
+}
@@ -0,0 +1,168 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/jsurloppe/fbxapi"
+)
+
+const DEFAULT_POLL_INTERVAL = 30 * time.Second
+
+// Engine polls the downloads and runs the first matching rule on each
+// download once it is done, a torrent being processed when it stops seeding.
+// Failed downloads are ignored.
+type Engine struct {
+	client Client
+	rules  []Rule
+	// Interval is the delay between two polls, DEFAULT_POLL_INTERVAL when
+	// zero.
+	Interval time.Duration
+	// ProcessExisting processes the downloads already finished when Run
+	// starts, they are skipped otherwise.
+	ProcessExisting bool
+	Log             logrus.FieldLogger
+	seen            map[int]bool
+}
+
+// NewEngine checks the rules and compiles their patterns.
+func NewEngine(client Client, rules []Rule) (*Engine, error) {
+	engine := &Engine{
+		client: client,
+		rules:  make([]Rule, len(rules)),
+		Log:    logrus.StandardLogger(),
+		seen:   make(map[int]bool),
+	}
+	copy(engine.rules, rules)
+
+	for i := range engine.rules {
+		if err := engine.rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+func finished(download *fbxapi.Download) bool {
+	return download.Status == fbxapi.DOWNLOAD_STATUS_DONE
+}
+
+// Run polls the downloads until ctx is done. Errors of a single download are
+// logged and do not stop the engine, it is not retried.
+func (e *Engine) Run(ctx context.Context) error {
+	interval := e.Interval
+	if interval == 0 {
+		interval = DEFAULT_POLL_INTERVAL
+	}
+
+	if !e.ProcessExisting {
+		downloads, err := e.client.ListDownloads()
+		if err != nil {
+			return err
+		}
+		for i := range downloads {
+			if finished(&downloads[i]) {
+				e.seen[downloads[i].ID] = true
+			}
+		}
+	}
+
+	for {
+		if err := e.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.Log.WithError(err).Warn("listing downloads failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Poll processes the downloads finished since the previous call.
+func (e *Engine) Poll(ctx context.Context) error {
+	downloads, err := e.client.ListDownloads()
+	if err != nil {
+		return err
+	}
+
+	for i := range downloads {
+		download := &downloads[i]
+		if !finished(download) || e.seen[download.ID] {
+			continue
+		}
+		e.seen[download.ID] = true
+
+		if _, err := e.Process(ctx, download); err != nil {
+			e.Log.WithError(err).WithField("download", download.Name).Error("post-processing failed")
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Process runs the first rule matching download and returns the job, nil
+// when no rule matched.
+func (e *Engine) Process(ctx context.Context, download *fbxapi.Download) (*Job, error) {
+	log := e.Log.WithFields(logrus.Fields{
+		"download": download.Name,
+		"id":       download.ID,
+	})
+
+	var announces []string
+	trackers := func() ([]string, error) {
+		if announces != nil || download.Type != "bt" {
+			return announces, nil
+		}
+		trackers, err := e.client.DownloadTrackers(download.ID)
+		if err != nil {
+			return nil, err
+		}
+		announces = []string{}
+		for _, tracker := range trackers {
+			announces = append(announces, tracker.Announce)
+		}
+		return announces, nil
+	}
+
+	for i := range e.rules {
+		rule := &e.rules[i]
+		matched, err := rule.Match.matches(download, trackers)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+
+		job := &Job{
+			Client:   e.client,
+			Download: download,
+			Path:     path.Join(string(download.DownloadDir), download.Name),
+			Log:      log.WithField("rule", rule.Name),
+		}
+		job.Log.Info("rule matched")
+
+		for _, action := range rule.Actions {
+			step := fmt.Sprintf("%T", action)
+			job.Log.WithField("action", step).Info("running action")
+			if err := action.Run(ctx, job); err != nil {
+				return job, fmt.Errorf("rule %q, %s: %v", rule.Name, step, err)
+			}
+		}
+		job.Log.WithField("path", job.Path).Info("post-processing done")
+		return job, nil
+	}
+
+	log.Debug("no rule matched")
+	return nil, nil
+}
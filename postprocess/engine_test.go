@@ -0,0 +1,195 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/jsurloppe/fbxapi"
+)
+
+// stubClient serves Info from dirs and files and records the calls changing
+// the box.
+type stubClient struct {
+	downloads []fbxapi.Download
+	dirs      map[string]bool
+	files     map[string]bool
+	calls     []string
+}
+
+func (s *stubClient) record(format string, args ...interface{}) *fbxapi.FSTask {
+	s.calls = append(s.calls, fmt.Sprintf(format, args...))
+	return &fbxapi.FSTask{ID: len(s.calls)}
+}
+
+func (s *stubClient) ListDownloads() ([]fbxapi.Download, error) {
+	return s.downloads, nil
+}
+
+func (s *stubClient) DownloadTrackers(id int) ([]fbxapi.DownloadTracker, error) {
+	return nil, nil
+}
+
+func (s *stubClient) EraseDownload(id int) error {
+	s.record("erase %d", id)
+	return nil
+}
+
+func (s *stubClient) Info(p string) (*fbxapi.FileInfo, error) {
+	switch {
+	case s.dirs[p]:
+		return &fbxapi.FileInfo{Path: fbxapi.FilePath(p), Name: path.Base(p), Type: fbxapi.FILE_TYPE_DIR}, nil
+	case s.files[p]:
+		return &fbxapi.FileInfo{Path: fbxapi.FilePath(p), Name: path.Base(p), Type: fbxapi.FILE_TYPE_FILE}, nil
+	}
+	return nil, &fbxapi.APIError{Code: fbxapi.API_ERROR_PATH_NOT_FOUND, Msg: "not found"}
+}
+
+func (s *stubClient) Ls(p string, onlyFolder, countSubFolder, removeHidden bool) ([]fbxapi.FileInfo, error) {
+	var infos []fbxapi.FileInfo
+	for file := range s.files {
+		if path.Dir(file) == p {
+			infos = append(infos, fbxapi.FileInfo{Name: path.Base(file), Type: fbxapi.FILE_TYPE_FILE})
+		}
+	}
+	return infos, nil
+}
+
+func (s *stubClient) Mkdir(parent, dirname string) (string, error) {
+	dir := path.Join(parent, dirname)
+	if s.dirs[dir] {
+		return "", &fbxapi.APIError{Code: fbxapi.API_ERROR_DESTINATION_CONFLICT, Msg: "exists"}
+	}
+	s.dirs[dir] = true
+	s.record("mkdir %s", dir)
+	return dir, nil
+}
+
+func (s *stubClient) Move(paths []string, dstDir, mode string) (*fbxapi.FSTask, error) {
+	return s.record("move %s to %s", strings.Join(paths, ", "), dstDir), nil
+}
+
+func (s *stubClient) Rename(p, newName string) (*fbxapi.FileInfo, error) {
+	s.record("rename %s to %s", p, newName)
+	dst := path.Join(path.Dir(p), newName)
+	return &fbxapi.FileInfo{Path: fbxapi.FilePath(dst), Name: newName}, nil
+}
+
+func (s *stubClient) Remove(paths ...string) (*fbxapi.FSTask, error) {
+	return s.record("remove %s", strings.Join(paths, ", ")), nil
+}
+
+func (s *stubClient) Extract(src, dst string, opts *fbxapi.ExtractOptions) (*fbxapi.FSTask, error) {
+	return s.record("extract %s into %s", src, dst), nil
+}
+
+func (s *stubClient) WaitTask(ctx context.Context, id int) (*fbxapi.FSTask, error) {
+	return &fbxapi.FSTask{ID: id, State: fbxapi.FS_TASK_STATE_DONE}, nil
+}
+
+func (s *stubClient) CreateShareLinkFor(p string, d time.Duration) (*fbxapi.ShareLink, error) {
+	s.record("share %s", p)
+	return &fbxapi.ShareLink{Path: fbxapi.FilePath(p)}, nil
+}
+
+func TestProcess(t *testing.T) {
+	const downloads = "/Disque dur/Téléchargements"
+
+	for _, existing := range []bool{false, true} {
+		client := &stubClient{
+			dirs:  map[string]bool{downloads: true, downloads + "/movie": existing},
+			files: map[string]bool{downloads + "/movie.part01.rar": true},
+		}
+
+		rules := []Rule{
+			{Name: "series", Match: Match{Name: `S\d+E\d+`}, Actions: []Action{&Erase{}}},
+			{Name: "movies", Match: Match{Type: "http"}, Actions: []Action{
+				&Extract{},
+				&Rename{Pattern: `^movie$`, Replace: "Movie (2017)"},
+				&Move{Dir: "/Disque dur/Films"},
+				&Share{},
+				&Erase{},
+			}},
+		}
+		engine, err := NewEngine(client, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log := logrus.New()
+		log.Out = ioutil.Discard
+		engine.Log = log
+
+		download := &fbxapi.Download{
+			ID:          7,
+			Name:        "movie.part01.rar",
+			Type:        "http",
+			DownloadDir: downloads,
+		}
+		job, err := engine.Process(context.Background(), download)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			"extract " + downloads + "/movie.part01.rar into " + downloads + "/movie",
+			"rename " + downloads + "/movie to Movie (2017)",
+			"move " + downloads + "/Movie (2017) to /Disque dur/Films",
+			"share /Disque dur/Films/Movie (2017)",
+			"erase 7",
+		}
+		if !existing {
+			want = append([]string{"mkdir " + downloads + "/movie"}, want...)
+		}
+		if !reflect.DeepEqual(client.calls, want) {
+			t.Fatalf("existing folder %v, unexpected calls:\n%s", existing, strings.Join(client.calls, "\n"))
+		}
+		if job.Path != "/Disque dur/Films/Movie (2017)" || job.ShareLink == nil {
+			t.Fatalf("unexpected job %+v", job)
+		}
+	}
+}
+
+func TestPollSeeding(t *testing.T) {
+	client := &stubClient{downloads: []fbxapi.Download{{ID: 3, Type: "bt", Status: fbxapi.DOWNLOAD_STATUS_SEEDING}}}
+	engine, err := NewEngine(client, []Rule{{Name: "torrents", Match: Match{Type: "bt"}, Actions: []Action{&Erase{}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	engine.Log = log
+
+	if err = engine.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.calls) != 0 {
+		t.Fatalf("seeding download processed: %v", client.calls)
+	}
+
+	client.downloads[0].Status = fbxapi.DOWNLOAD_STATUS_DONE
+	if err = engine.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(client.calls, []string{"erase 3"}) {
+		t.Fatalf("unexpected calls %v", client.calls)
+	}
+}
+
+func TestProcessNoMatch(t *testing.T) {
+	client := &stubClient{}
+	engine, err := NewEngine(client, []Rule{{Name: "nzb", Match: Match{Type: "nzb"}, Actions: []Action{&Erase{}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := engine.Process(context.Background(), &fbxapi.Download{ID: 1, Type: "bt"})
+	if err != nil || job != nil || len(client.calls) != 0 {
+		t.Fatalf("unexpected processing: %v %v %v", job, err, client.calls)
+	}
+}
@@ -0,0 +1,89 @@
+// Package postprocess runs actions on the finished downloads of a Freebox,
+// such as extracting archives and moving the result to a library folder,
+// according to a list of rules.
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/jsurloppe/fbxapi"
+)
+
+// Match selects downloads, empty fields match everything.
+type Match struct {
+	// Name is a regular expression matched against the download name.
+	Name string
+	// Type is the download type, such as "bt", "nzb", "http" or "ftp".
+	Type string
+	// MinSize and MaxSize bound the download size in bytes, 0 meaning no
+	// bound.
+	MinSize int
+	MaxSize int
+	// Tracker is a regular expression matched against the announce urls of
+	// torrents, other downloads never match it.
+	Tracker string
+
+	name    *regexp.Regexp
+	tracker *regexp.Regexp
+}
+
+func (m *Match) compile() (err error) {
+	if m.Name != "" {
+		if m.name, err = regexp.Compile(m.Name); err != nil {
+			return
+		}
+	}
+	if m.Tracker != "" {
+		m.tracker, err = regexp.Compile(m.Tracker)
+	}
+	return
+}
+
+// matches reports whether download is selected, trackers returns the
+// announce urls of the download and is only called when needed.
+func (m *Match) matches(download *fbxapi.Download, trackers func() ([]string, error)) (bool, error) {
+	if m.name != nil && !m.name.MatchString(download.Name) {
+		return false, nil
+	}
+	if m.Type != "" && m.Type != download.Type {
+		return false, nil
+	}
+	if m.MinSize > 0 && download.Size < m.MinSize {
+		return false, nil
+	}
+	if m.MaxSize > 0 && download.Size > m.MaxSize {
+		return false, nil
+	}
+	if m.tracker == nil {
+		return true, nil
+	}
+
+	announces, err := trackers()
+	if err != nil {
+		return false, err
+	}
+	for _, announce := range announces {
+		if m.tracker.MatchString(announce) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Rule runs its actions, in order, on the downloads selected by Match.
+type Rule struct {
+	Name    string
+	Match   Match
+	Actions []Action
+}
+
+func (r *Rule) compile() error {
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("rule %q has no action", r.Name)
+	}
+	if err := r.Match.compile(); err != nil {
+		return fmt.Errorf("rule %q: %v", r.Name, err)
+	}
+	return nil
+}
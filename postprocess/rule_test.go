@@ -0,0 +1,98 @@
+package postprocess
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jsurloppe/fbxapi"
+)
+
+func TestMatch(t *testing.T) {
+	download := &fbxapi.Download{
+		Name: "Some.Show.S01E02.720p",
+		Type: "bt",
+		Size: 500000000,
+	}
+	trackers := func() ([]string, error) {
+		return []string{"udp://tracker.example.org:1337/announce"}, nil
+	}
+
+	tests := []struct {
+		match Match
+		want  bool
+	}{
+		{Match{}, true},
+		{Match{Name: `S\d+E\d+`}, true},
+		{Match{Name: `^Movie`}, false},
+		{Match{Type: "nzb"}, false},
+		{Match{MinSize: 1000000000}, false},
+		{Match{MaxSize: 1000000000}, true},
+		{Match{Tracker: `example\.org`}, true},
+		{Match{Name: `S\d+E\d+`, Tracker: `other\.org`}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.match.compile(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := test.match.matches(download, trackers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%+v: expected %v, got %v", test.match, test.want, got)
+		}
+	}
+}
+
+func TestMatchTrackerError(t *testing.T) {
+	match := Match{Tracker: "."}
+	if err := match.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := match.matches(&fbxapi.Download{Type: "bt"}, func() ([]string, error) {
+		return nil, errors.New("unreachable")
+	})
+	if err == nil {
+		t.Fatal("tracker error not returned")
+	}
+}
+
+func TestNewEngine(t *testing.T) {
+	if _, err := NewEngine(nil, []Rule{{Name: "empty"}}); err == nil {
+		t.Fatal("rule without action accepted")
+	}
+	if _, err := NewEngine(nil, []Rule{{Name: "bad", Match: Match{Name: "("}, Actions: []Action{&Erase{}}}}); err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+}
+
+func TestArchiveNames(t *testing.T) {
+	for name, want := range map[string]bool{
+		"movie.rar":        true,
+		"movie.part01.rar": true,
+		"movie.part02.rar": false,
+		"movie.part10.rar": false,
+		"movie.tar.gz":     true,
+		"movie.mkv":        false,
+	} {
+		if got := isArchive(name) && isFirstVolume(name); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestArchiveBase(t *testing.T) {
+	for name, want := range map[string]string{
+		"movie.rar":        "movie",
+		"movie.part01.rar": "movie",
+		"movie.tar.gz":     "movie",
+		"Movie.TGZ":        "Movie",
+		"movie.7z":         "movie",
+	} {
+		if got := archiveBase(name); got != want {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package fbxapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var jsonNull = []byte("null")
+
+// Timestamp is a date sent by the box as unix seconds, 0 meaning no date and
+// being decoded as the zero time.
+type Timestamp struct {
+	time.Time
+}
+
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{t}
+}
+
+func (ts *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		ts.Time = time.Time{}
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return err
+	}
+
+	if seconds == 0 {
+		ts.Time = time.Time{}
+	} else {
+		ts.Time = time.Unix(int64(seconds), 0)
+	}
+	return nil
+}
+
+func (ts Timestamp) MarshalJSON() ([]byte, error) {
+	if ts.IsZero() {
+		return []byte("0"), nil
+	}
+	return []byte(strconv.FormatInt(ts.Unix(), 10)), nil
+}
+
+// Duration is a delay sent by the box as a number of seconds.
+type Duration struct {
+	time.Duration
+}
+
+func NewDuration(d time.Duration) Duration {
+	return Duration{d}
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		d.Duration = 0
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = time.Duration(seconds * float64(time.Second))
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(d.Seconds()), 10)), nil
+}
+
+// FilePath is a path on the box. It is exchanged base64 encoded and holds the
+// decoded path.
+type FilePath string
+
+func (p FilePath) String() string {
+	return string(p)
+}
+
+func (p *FilePath) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	*p = FilePath(decoded)
+	return nil
+}
+
+func (p FilePath) MarshalJSON() ([]byte, error) {
+	if p == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal(EncodePath(string(p)))
+}
+
+// enum is implemented by the string types whose values are documented by the
+// API. Values unknown to this package, sent by newer firmwares, are kept as
+// is when decoding and encoding.
+type enum interface {
+	fmt.Stringer
+	Known() bool
+}
+
+// checkEnum catches typos in a value about to be sent to the box.
+func checkEnum(value enum, kind string) error {
+	if !value.Known() {
+		return fmt.Errorf("unknown %s %q", kind, value.String())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package fbxapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	var info FileInfo
+	err := json.Unmarshal([]byte(`{"modification": 1500000000}`), &info)
+	failOnError(t, err)
+	if !info.Modification.Equal(time.Unix(1500000000, 0)) {
+		t.Fatalf("unexpected modification %v", info.Modification)
+	}
+
+	err = json.Unmarshal([]byte(`{"modification": 0}`), &info)
+	failOnError(t, err)
+	if !info.Modification.IsZero() {
+		t.Fatalf("expected zero time, got %v", info.Modification)
+	}
+
+	data, err := json.Marshal(NewTimestamp(time.Unix(42, 0)))
+	failOnError(t, err)
+	if string(data) != "42" {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+
+	data, err = json.Marshal(Timestamp{})
+	failOnError(t, err)
+	if string(data) != "0" {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var task FSTask
+	err := json.Unmarshal([]byte(`{"duration": 90, "eta": null}`), &task)
+	failOnError(t, err)
+	if task.Duration.Duration != 90*time.Second || task.ETA.Duration != 0 {
+		t.Fatalf("unexpected durations %v %v", task.Duration, task.ETA)
+	}
+
+	data, err := json.Marshal(NewDuration(time.Minute))
+	failOnError(t, err)
+	if string(data) != "60" {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	const path = "/Disque dur/Vidéos"
+
+	data, err := json.Marshal(FilePath(path))
+	failOnError(t, err)
+	if string(data) != `"`+EncodePath(path)+`"` {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+
+	var decoded FilePath
+	err = json.Unmarshal(data, &decoded)
+	failOnError(t, err)
+	if decoded != path {
+		t.Fatalf("unexpected path %q", decoded)
+	}
+
+	err = json.Unmarshal([]byte(`"/not base64"`), &decoded)
+	if err == nil {
+		t.Fatalf("plain path decoded as %q", decoded)
+	}
+}
+
+func TestEnum(t *testing.T) {
+	var download Download
+	err := json.Unmarshal([]byte(`{"status": "seeding", "io_priority": "turbo"}`), &download)
+	failOnError(t, err)
+	if download.Status != DOWNLOAD_STATUS_SEEDING || !download.Status.IsTerminal() {
+		t.Fatalf("unexpected status %v", download.Status)
+	}
+	if download.IOPriority != "turbo" || download.IOPriority.Known() {
+		t.Fatalf("unknown priority not kept: %v", download.IOPriority)
+	}
+
+	// read-modify-write cycles must send unknown values back unchanged
+	data, err := json.Marshal(download.IOPriority)
+	failOnError(t, err)
+	if string(data) != `"turbo"` {
+		t.Fatalf("unknown priority not kept: %s", data)
+	}
+
+	data, err = json.Marshal(DOWNLOAD_IO_PRIORITY_HIGH)
+	failOnError(t, err)
+	if string(data) != `"high"` {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+
+	var task FSTask
+	err = json.Unmarshal([]byte(`{"type": "hash", "state": "running"}`), &task)
+	failOnError(t, err)
+	if task.Type != FS_TASK_TYPE_HASH || task.Finished() {
+		t.Fatalf("unexpected task %v %v", task.Type, task.State)
+	}
+}
+
+func TestEnumCheck(t *testing.T) {
+	// typos are caught before reaching the box, the client has no session
+	// and would fail on any request
+	client := new(Client)
+	if _, err := client.UpdateDownload(1, &DownloadUpdateReq{IOPriority: "hihg"}); err == nil || !strings.Contains(err.Error(), "hihg") {
+		t.Fatalf("unknown io priority sent: %v", err)
+	}
+	if err := client.SetThrottling("hibernat"); err == nil || !strings.Contains(err.Error(), "hibernat") {
+		t.Fatalf("unknown throttling mode sent: %v", err)
+	}
+	if _, err := client.UpdateDownloadFile(1, "1-0", "skip"); err == nil || !strings.Contains(err.Error(), "skip") {
+		t.Fatalf("unknown file priority sent: %v", err)
+	}
+	if _, err := client.SetDownloadFilesPriority(1, "", MatchDownloadFileGlob("*")); err == nil || !strings.Contains(err.Error(), "priority") {
+		t.Fatalf("empty file priority sent: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package fbxapi
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
 	"strconv"
 )
 
@@ -36,3 +39,41 @@ func APIVersionToInt(apiVersion string) (int, error) {
 	}
 	return int(f), nil
 }
+
+// toJSONMap returns the JSON object v encodes to, numbers kept as json.Number.
+func toJSONMap(v interface{}) (m map[string]interface{}, err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&m)
+	return
+}
+
+// jsonDiff returns the fields of changed whose value differs from the one in
+// current, nested objects being compared field by field. It builds partial
+// update bodies.
+func jsonDiff(current, changed map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for k, v := range changed {
+		old, ok := current[k]
+		if !ok {
+			diff[k] = v
+			continue
+		}
+		oldObject, oldIsObject := old.(map[string]interface{})
+		object, isObject := v.(map[string]interface{})
+		if oldIsObject && isObject {
+			if sub := jsonDiff(oldObject, object); len(sub) > 0 {
+				diff[k] = sub
+			}
+			continue
+		}
+		if !reflect.DeepEqual(old, v) {
+			diff[k] = v
+		}
+	}
+	return diff
+}